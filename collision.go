@@ -19,6 +19,9 @@ type CollisionInfo struct {
 	penetration float64
 	normal      Vec2
 	resolved    bool
+	restitution float64
+	friction    float64
+	mixed       bool //restitution et friction calculées par l'espace
 }
 
 //IsColliding retourne true s'il y a collision
@@ -46,6 +49,16 @@ func (i *CollisionInfo) Second() Shape {
 	return i.second
 }
 
+//Normal retourne la normale de la collision, orientée de la première vers la seconde forme
+func (i *CollisionInfo) Normal() Vec2 {
+	return i.normal
+}
+
+//Penetration retourne la profondeur de pénétration
+func (i *CollisionInfo) Penetration() float64 {
+	return i.penetration
+}
+
 //GetShapeForTag retourne les formes impliquées dans la collision
 // qui possèdent ce tag
 func (i *CollisionInfo) GetShapeForTag(t string) ([]Shape, error) {
@@ -97,10 +110,17 @@ func (i *CollisionInfo) Resolv() {
 	first := i.first
 	second := i.second
 
+	// Deux formes de masse infinie (statiques ou cinématiques) ne se repoussent pas
+	totInvMass := first.InvMass() + second.InvMass()
+	if totInvMass == 0 {
+		i.SetResolved(true)
+		return
+	}
+
 	// Détermination de l'impulsion a appliquer aux objets sur la normale de la collision
 
 	// vitesse relative
-	f2S := second.Velocity().Sub(first.Velocity())
+	f2S := relativeVelocity(first, second)
 
 	// vitesse relative sur la normale de la collision
 	vRelAlongNorm := f2S.DotProduct(i.normal)
@@ -110,11 +130,15 @@ func (i *CollisionInfo) Resolv() {
 		return
 	}
 
-	e := Min(first.Elasticity(), second.Elasticity())
+	if !i.mixed {
+		i.restitution, i.friction = defaultMaterials.Mix(first, second)
+		i.mixed = true
+	}
+	e := i.restitution
 
 	// calcule impulsion scalaire
 	j := -(1 + e) * vRelAlongNorm
-	j /= first.InvMass() + second.InvMass()
+	j /= totInvMass
 
 	impulse := i.normal.Mult(j)
 
@@ -122,14 +146,18 @@ func (i *CollisionInfo) Resolv() {
 	first.SetVelocity(first.Velocity().Sub(impulse.Mult(first.InvMass())))
 	second.SetVelocity(second.Velocity().Add(impulse.Mult(second.InvMass())))
 
-	//Applique friction
-	first.SetVelocity(first.Velocity().Mult(1 - first.Friction()))
-	second.SetVelocity(second.Velocity().Mult(1 - second.Friction()))
+	//Applique friction, sauf aux formes de masse infinie dont la vitesse est imposée
+	if first.InvMass() > 0 {
+		first.SetVelocity(first.Velocity().Mult(1 - i.friction))
+	}
+	if second.InvMass() > 0 {
+		second.SetVelocity(second.Velocity().Mult(1 - i.friction))
+	}
 
 	// Correction naufrage ("sinking"), "causé par le fait que "la résultante des vitesses
 	// est insuffisante pour pousser l'objet hors d'une collision, quand un objet est stationnaire"
 	percent := 0.5 // habituellement 20% à 80%
-	corr := i.normal.Mult(i.penetration / (first.InvMass() + second.InvMass()) * percent)
+	corr := i.normal.Mult(i.penetration / totInvMass * percent)
 
 	first.SetPos(first.Pos().Sub(corr.Mult(first.InvMass())))
 	second.SetPos(second.Pos().Add(corr.Mult(second.InvMass())))
@@ -138,6 +166,65 @@ func (i *CollisionInfo) Resolv() {
 	i.SetResolved(true)
 }
 
+//relativeVelocity retourne la vitesse de second relative à first
+// Une forme portée par un support cinématique est déplacée avec lui par Space:
+// sa vitesse est déjà relative à celui-ci
+func relativeVelocity(first Shape, second Shape) Vec2 {
+	switch {
+	case second.Ground() == first && first.IsKinematic():
+		return second.Velocity()
+	case first.Ground() == second && second.IsKinematic():
+		return first.Velocity().Neg()
+	default:
+		return second.Velocity().Sub(first.Velocity())
+	}
+}
+
+//setContactSides classe le contact pour chacune des deux formes: sol, mur ou plafond.
+// Le côté est déterminé par l'angle entre la normale du contact et le "haut" local
+// de la forme, opposé à sa gravité: un cosinus d'au moins minCos donne un sol,
+// d'au plus -minCos un plafond, sinon un mur.
+// Les formes statiques ne sont pas classées
+func setContactSides(info *CollisionInfo, minCos float64) {
+	// la normale va de first vers second
+	classifyContact(info.second, info.first, info.normal, minCos)
+	classifyContact(info.first, info.second, info.normal.Neg(), minCos)
+}
+
+//classifyContact classe le contact de other sur shape, n étant la normale orientée vers shape
+func classifyContact(shape Shape, other Shape, n Vec2, minCos float64) {
+	if shape.IsStatic() {
+		return
+	}
+
+	up := upDirection(shape)
+	right := Vec2{-up.Y, up.X}
+
+	switch cos := n.DotProduct(up); {
+	case cos >= minCos:
+		groundOn(shape, other, n)
+	case cos <= -minCos:
+		shape.AddTouching(SideCeiling, other)
+	case n.DotProduct(right) > 0:
+		// un mur à gauche repousse vers la droite
+		shape.AddTouching(SideWallLeft, other)
+	default:
+		shape.AddTouching(SideWallRight, other)
+	}
+}
+
+//groundOn mets shape au sol sur ground, n étant la normale orientée vers shape
+// Si la forme touche plusieurs sols, le plus plat est retenu
+func groundOn(shape Shape, ground Shape, n Vec2) {
+	up := upDirection(shape)
+	if shape.IsGrounded() && shape.GroundNormal().DotProduct(up) >= n.DotProduct(up) {
+		return
+	}
+	shape.SetGrounded(true)
+	shape.SetGround(ground)
+	shape.SetGroundNormal(n)
+}
+
 //Separate sépare deux objets en revenant à une position pré-collision
 func (i *CollisionInfo) Separate() {
 	// Ne résoud pas plusieurs fois
@@ -150,6 +237,10 @@ func (i *CollisionInfo) Separate() {
 
 	// Repositionnement des objets qui s'interpénètrent
 	totInvMass := first.InvMass() + second.InvMass()
+	if totInvMass == 0 {
+		i.SetResolved(true)
+		return
+	}
 	first.SetPos(first.Pos().Sub(i.normal.Mult(i.penetration * first.InvMass() / totInvMass)))
 	second.SetPos(second.Pos().Add(i.normal.Mult(i.penetration * second.InvMass() / totInvMass)))
 
@@ -193,15 +284,6 @@ func AABBvsAABB(first *Rectangle, second *Rectangle) *CollisionInfo {
 		info.penetration = py
 	}
 
-	// grounded si normale.Y == -1 et corps a une masse
-	if info.normal.Y == -1 {
-		if first.mass != 0 {
-			first.SetGrounded(true)
-		}
-		if second.mass != 0 {
-			second.SetGrounded(true)
-		}
-	}
 	return info
 }
 
@@ -234,17 +316,6 @@ func CirclevsCircle(first *Circle, second *Circle) *CollisionInfo {
 		info.normal = Vec2{1, 0}
 	}
 
-	//TODO: c'est quoi ça?
-	// grounded si normale.Y == -1 et corps a une masse
-	if info.normal.Y == -1 {
-		if first.mass != 0 {
-			first.SetGrounded(true)
-		}
-		if second.mass != 0 {
-			second.SetGrounded(true)
-		}
-	}
-
 	return info
 
 }
@@ -265,56 +336,28 @@ func AABBvsCircle(first *Rectangle, second *Circle) *CollisionInfo {
 	closest.X = Clamp(closest.X, -xExtent, xExtent)
 	closest.Y = Clamp(closest.Y, -yExtent, yExtent)
 
-	closest = first.Center().Add(closest)
-
-	inside := false
-
-	// Cercle est dans AABB
 	if n == closest {
-		inside = true
-
-		// Axe le plus proche
-		if Abs(n.X) > Abs(n.X) {
-			if closest.X > 0 {
-				closest.X = xExtent
-			} else {
-				closest.X = -xExtent
-			}
+		// Cercle est dans AABB: le centre est repoussé par la face la plus proche
+		if xExtent-Abs(n.X) < yExtent-Abs(n.Y) {
+			info.normal = Vec2{Sign(n.X), 0}
+			info.penetration = second.Radius() + xExtent - Abs(n.X)
 		} else {
-			if closest.Y > 0 {
-				closest.Y = yExtent
-			} else {
-				closest.Y = -yExtent
-			}
+			info.normal = Vec2{0, Sign(n.Y)}
+			info.penetration = second.Radius() + yExtent - Abs(n.Y)
 		}
-	}
-
-	dist := second.Center().Distance(closest)
-	if dist > second.Radius() && !inside {
-		return info
-	}
-
-	info.second = second
-	normale := second.Center().Sub(closest).Normalize()
-
-	if inside {
-		info.normal = normale.Neg()
 	} else {
-		info.normal = normale
-	}
-
-	info.penetration = second.Radius() - dist
-
-	// grounded si normale.Y == -1 et corps a une masse
-	if info.normal.Y == -1 {
-		if first.mass != 0 {
-			first.SetGrounded(true)
-		}
-		if second.mass != 0 {
-			second.SetGrounded(true)
+		// closest est relatif au centre du rectangle, comme n
+		toCenter := n.Sub(closest)
+		dist := toCenter.Length()
+		if dist > second.Radius() {
+			return info
 		}
 
+		info.normal = toCenter.Div(dist)
+		info.penetration = second.Radius() - dist
 	}
 
+	info.second = second
+
 	return info
 }
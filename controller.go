@@ -0,0 +1,211 @@
+package physics
+
+import "math"
+
+//ControllerConfig paramètres d'un CharacterController
+// Les vitesses sont exprimées par tick, les durées en ticks, les angles en radians
+type ControllerConfig struct {
+	WalkSpeed      float64 //vitesse de marche
+	RunSpeed       float64 //vitesse de course
+	GroundAccel    float64 //accélération au sol
+	AirAccel       float64 //accélération en l'air
+	Decel          float64 //décélération sans commande
+	JumpSpeed      float64 //vitesse initiale du saut
+	JumpCut        float64 //facteur appliqué à la vitesse montante quand le saut est relâché
+	CoyoteTime     float64 //ticks pendant lesquels on peut encore sauter après avoir quitté le sol
+	JumpBuffer     float64 //ticks pendant lesquels un saut demandé trop tôt est retenu
+	WallSlideSpeed float64 //vitesse de chute maximale contre un mur, 0 pour désactiver
+	MaxSlope       float64 //angle maximal d'un sol praticable, 0 pour celui de l'espace
+}
+
+//DefaultControllerConfig retourne une configuration de départ raisonnable
+func DefaultControllerConfig() ControllerConfig {
+	return ControllerConfig{
+		WalkSpeed:      3,
+		RunSpeed:       5,
+		GroundAccel:    0.6,
+		AirAccel:       0.3,
+		Decel:          0.8,
+		JumpSpeed:      9,
+		JumpCut:        0.5,
+		CoyoteTime:     6,
+		JumpBuffer:     6,
+		WallSlideSpeed: 2,
+		MaxSlope:       math.Pi / 4,
+	}
+}
+
+//ControllerInput commandes du joueur pour un tick
+type ControllerInput struct {
+	Move float64 //direction horizontale, de -1 (gauche) à 1 (droite)
+	Run  bool
+	Jump bool //bouton de saut enfoncé
+}
+
+//CharacterController contrôleur de personnage de plateforme
+// Il pilote la vitesse d'une forme dynamique à partir des contacts calculés par
+// l'espace au dernier pas. Update doit être appelée une fois par tick, avant Space.Update
+type CharacterController struct {
+	space  *Space
+	shape  Shape
+	config ControllerConfig
+
+	grounded     bool
+	groundNormal Vec2
+	wall         float64 //-1 mur à gauche, 1 mur à droite, 0 sinon
+	coyote       float64
+	buffer       float64
+	jumping      bool
+	jumpHeld     bool
+}
+
+//NewCharacterController crée un contrôleur pour shape dans space
+func NewCharacterController(space *Space, shape Shape) *CharacterController {
+	return &CharacterController{space: space, shape: shape, config: DefaultControllerConfig()}
+}
+
+//Shape retourne la forme pilotée
+func (c *CharacterController) Shape() Shape {
+	return c.shape
+}
+
+//Config retourne la configuration
+func (c *CharacterController) Config() ControllerConfig {
+	return c.config
+}
+
+//SetConfig mets la configuration à cfg
+func (c *CharacterController) SetConfig(cfg ControllerConfig) {
+	c.config = cfg
+}
+
+//IsGrounded retourne true si la forme touchait un sol praticable au dernier pas
+func (c *CharacterController) IsGrounded() bool {
+	return c.grounded
+}
+
+//GroundNormal retourne la normale du sol, nulle si pas au sol
+func (c *CharacterController) GroundNormal() Vec2 {
+	return c.groundNormal
+}
+
+//Wall retourne -1 si la forme touche un mur à gauche, 1 à droite, 0 sinon
+func (c *CharacterController) Wall() float64 {
+	return c.wall
+}
+
+//IsJumping retourne true pendant la montée d'un saut
+func (c *CharacterController) IsJumping() bool {
+	return c.jumping
+}
+
+//readContacts lit sol et murs calculés par l'espace au dernier pas
+// L'espace classe en mur tout sol plus raide que son angle maximal: MaxSlope ne peut
+// que restreindre les sols praticables. Un sol trop raide pour MaxSlope devient un mur
+func (c *CharacterController) readContacts() {
+	c.grounded = c.shape.IsGrounded()
+	c.groundNormal = c.shape.GroundNormal()
+
+	switch {
+	case c.shape.OnWallLeft():
+		c.wall = -1
+	case c.shape.OnWallRight():
+		c.wall = 1
+	default:
+		c.wall = 0
+	}
+
+	if !c.grounded || c.config.MaxSlope <= 0 || c.config.MaxSlope >= c.space.MaxGroundAngle() {
+		return
+	}
+	up := upDirection(c.shape)
+	if c.groundNormal.DotProduct(up) < math.Cos(c.config.MaxSlope) {
+		if c.wall == 0 {
+			// un mur à gauche repousse vers la droite
+			right := Vec2{-up.Y, up.X}
+			c.wall = -Sign(c.groundNormal.DotProduct(right))
+		}
+		c.grounded = false
+		c.groundNormal = Vec2{}
+	}
+}
+
+//Update applique les commandes du tick à la vitesse de la forme
+func (c *CharacterController) Update(in ControllerInput) {
+	c.readContacts()
+	cfg := c.config
+
+	up := upDirection(c.shape)
+	right := Vec2{-up.Y, up.X}
+	v := c.shape.Velocity()
+	vx, vy := v.DotProduct(right), v.DotProduct(up)
+
+	// horizontal
+	speed := cfg.WalkSpeed
+	if in.Run {
+		speed = cfg.RunSpeed
+	}
+	move := Clamp(in.Move, -1, 1)
+	switch {
+	case move == 0:
+		vx = approach(vx, 0, cfg.Decel)
+	case c.grounded:
+		vx = approach(vx, move*speed, cfg.GroundAccel)
+	default:
+		vx = approach(vx, move*speed, cfg.AirAccel)
+	}
+
+	// coyote time et mémoire du saut
+	if c.grounded {
+		c.coyote = cfg.CoyoteTime
+		if vy <= 0 {
+			c.jumping = false
+		}
+	} else if c.coyote > 0 {
+		c.coyote--
+	}
+
+	pressed := in.Jump && !c.jumpHeld
+	c.jumpHeld = in.Jump
+	if pressed {
+		c.buffer = cfg.JumpBuffer
+	} else if c.buffer > 0 {
+		c.buffer--
+	}
+
+	if (pressed || c.buffer > 0) && (c.grounded || c.coyote > 0) {
+		vy = cfg.JumpSpeed
+		c.jumping = true
+		c.buffer = 0
+		c.coyote = 0
+	} else if c.jumping && !in.Jump && vy > 0 {
+		// saut à hauteur variable: relâcher coupe la montée
+		vy *= cfg.JumpCut
+		c.jumping = false
+	}
+
+	// glissade le long d'un mur vers lequel on pousse
+	if !c.grounded && c.wall != 0 && move*c.wall > 0 && cfg.WallSlideSpeed > 0 {
+		vy = Max(vy, -cfg.WallSlideSpeed)
+	}
+
+	if c.grounded && !c.jumping {
+		// suit la pente du sol plutôt que l'horizontale
+		tangent := Vec2{-c.groundNormal.Y, c.groundNormal.X}
+		if tangent.DotProduct(right) < 0 {
+			tangent = tangent.Neg()
+		}
+		c.shape.SetVelocity(tangent.Mult(vx))
+		return
+	}
+
+	c.shape.SetVelocity(right.Mult(vx).Add(up.Mult(vy)))
+}
+
+//approach rapproche value de target d'au plus step
+func approach(value float64, target float64, step float64) float64 {
+	if value < target {
+		return Min(value+step, target)
+	}
+	return Max(value-step, target)
+}
@@ -0,0 +1,116 @@
+package physics
+
+//CrushEvent une forme écrasée entre des formes immobiles ou cinématiques
+// qui la poussent en sens opposés
+type CrushEvent struct {
+	shape    Shape
+	crushers []Shape
+}
+
+//Shape retourne la forme écrasée
+func (e *CrushEvent) Shape() Shape {
+	return e.shape
+}
+
+//Crushers retourne les formes qui écrasent
+func (e *CrushEvent) Crushers() []Shape {
+	return e.crushers
+}
+
+const (
+	defaultCrushPenetration float64 = 1
+	// deux contacts s'opposent si l'angle entre leurs normales dépasse ~135°
+	crushOpposingCos float64 = -0.7
+)
+
+//crushContact un contact vu depuis la forme qui le subit
+type crushContact struct {
+	other       Shape
+	normal      Vec2 // orientée vers la forme
+	penetration float64
+}
+
+//SetCrushThreshold mets la pénétration au-delà de laquelle deux contacts opposés
+// écrasent une forme. 0 rétablit la valeur par défaut
+func (s *Space) SetCrushThreshold(p float64) {
+	s.crushDepth = p
+}
+
+//CrushThreshold retourne la pénétration d'écrasement
+func (s *Space) CrushThreshold() float64 {
+	if s.crushDepth <= 0 {
+		return defaultCrushPenetration
+	}
+	return s.crushDepth
+}
+
+//SetCrushHandler mets la fonction appelée à chaque écrasement, nil pour aucune
+// Elle reçoit aussi les écrasements d'Actor par un Solid, hors de Update
+func (s *Space) SetCrushHandler(f func(*CrushEvent)) {
+	s.onCrush = f
+}
+
+//Crushes retourne les écrasements depuis le début du dernier Update
+func (s *Space) Crushes() []*CrushEvent {
+	return s.crushes
+}
+
+//detectCrushes cherche les formes dynamiques prises entre deux formes de masse infinie
+// (statiques ou cinématiques) qui les poussent en sens opposés, avec une pénétration
+// cumulée au-delà du seuil: le solveur ne peut pas les séparer.
+// Une pile de formes dynamiques au repos n'est donc pas un écrasement
+func (s *Space) detectCrushes() {
+	contacts := map[Shape][]crushContact{}
+	order := []Shape{}
+	add := func(shape Shape, c crushContact) {
+		if shape.InvMass() <= 0 || c.other.InvMass() > 0 {
+			return
+		}
+		if _, exists := contacts[shape]; !exists {
+			order = append(order, shape)
+		}
+		contacts[shape] = append(contacts[shape], c)
+	}
+
+	for _, info := range s.collisions.GetAll() {
+		add(info.second, crushContact{info.first, info.normal, info.penetration})
+		add(info.first, crushContact{info.second, info.normal.Neg(), info.penetration})
+	}
+
+	threshold := s.CrushThreshold()
+	for _, shape := range order {
+		crushers := []Shape{}
+		list := contacts[shape]
+		for i := 0; i < len(list)-1; i++ {
+			for j := i + 1; j < len(list); j++ {
+				if list[i].normal.DotProduct(list[j].normal) > crushOpposingCos ||
+					list[i].penetration+list[j].penetration <= threshold {
+					continue
+				}
+				crushers = appendShapeOnce(crushers, list[i].other)
+				crushers = appendShapeOnce(crushers, list[j].other)
+			}
+		}
+		if len(crushers) > 0 {
+			s.emitCrush(&CrushEvent{shape: shape, crushers: crushers})
+		}
+	}
+}
+
+//emitCrush enregistre un écrasement et appelle le handler
+func (s *Space) emitCrush(e *CrushEvent) {
+	s.crushes = append(s.crushes, e)
+	if s.onCrush != nil {
+		s.onCrush(e)
+	}
+}
+
+//appendShapeOnce ajoute shape à la liste si elle n'y est pas
+func appendShapeOnce(shapes []Shape, shape Shape) []Shape {
+	for _, sh := range shapes {
+		if sh == shape {
+			return shapes
+		}
+	}
+	return append(shapes, shape)
+}
@@ -0,0 +1,53 @@
+package physics
+
+import "math"
+
+//SetDrag mets les coefficients de résistance par défaut de l'espace
+// Ils s'appliquent aux formes qui n'ont pas leur propre résistance (voir Shape.SetDrag)
+func (s *Space) SetDrag(linear float64, quadratic float64) {
+	s.linearDrag = linear
+	s.quadDrag = quadratic
+}
+
+//Drag retourne les coefficients de résistance par défaut de l'espace
+func (s *Space) Drag() (float64, float64) {
+	return s.linearDrag, s.quadDrag
+}
+
+//applyDrag freine la forme avant intégration
+// Les formes cinématiques ne sont pas freinées: leur vitesse est imposée
+func (s *Space) applyDrag(shape Shape) {
+	if shape.IsKinematic() {
+		return
+	}
+
+	linear, quadratic := s.linearDrag, s.quadDrag
+	if !shape.UsesSpaceDrag() {
+		linear, quadratic = shape.Drag()
+	}
+	if linear <= 0 && quadratic <= 0 {
+		return
+	}
+
+	v := shape.Velocity()
+	if v == (Vec2{}) {
+		return
+	}
+	shape.SetVelocity(dampVelocity(v, linear, quadratic))
+}
+
+//dampVelocity retourne la vitesse v après un tick de résistance
+// On utilise la solution exacte sur un tick plutôt qu'un pas d'Euler:
+//	dv/dt = -linear*v       donne v*exp(-linear)
+//	dv/dt = -quadratic*v*|v| donne v/(1 + quadratic*|v|)
+// La vitesse ne change jamais de sens et la décroissance ne dépend pas
+// du découpage du temps, même avec de grands coefficients
+func dampVelocity(v Vec2, linear float64, quadratic float64) Vec2 {
+	if linear > 0 {
+		v = v.Mult(math.Exp(-linear))
+	}
+	if quadratic > 0 {
+		v = v.Div(1 + quadratic*v.Length())
+	}
+	return v
+}
@@ -0,0 +1,162 @@
+package physics
+
+//EffectorMode détermine comment un effecteur agit sur les formes
+type EffectorMode int
+
+const (
+	//EffectorForce applique une force: les formes lourdes sont moins affectées (vent, courant)
+	EffectorForce EffectorMode = iota
+	//EffectorAcceleration applique une accélération indépendante de la masse (gravité locale)
+	EffectorAcceleration
+)
+
+//AreaEffector région qui applique une force à toutes les formes qui la chevauchent
+// La région n'a pas de réponse de collision: elle n'a pas besoin d'être dans l'espace,
+// mais peut y être ajoutée (non solide) pour être déplacée comme les autres formes
+type AreaEffector struct {
+	region    Shape
+	direction Vec2
+	magnitude float64
+	falloff   float64
+	tags      []string
+	mode      EffectorMode
+}
+
+//NewAreaEffector crée un effecteur sur la région region qui pousse
+// dans la direction direction avec l'intensité magnitude
+func NewAreaEffector(region Shape, direction Vec2, magnitude float64) *AreaEffector {
+	e := &AreaEffector{region: region, magnitude: magnitude}
+	e.SetDirection(direction)
+	return e
+}
+
+//Region retourne la forme de la région
+func (e *AreaEffector) Region() Shape {
+	return e.region
+}
+
+//Direction retourne la direction (normalisée) de la force
+func (e *AreaEffector) Direction() Vec2 {
+	return e.direction
+}
+
+//SetDirection mets la direction de la force à d
+func (e *AreaEffector) SetDirection(d Vec2) {
+	if d == (Vec2{}) {
+		e.direction = d
+		return
+	}
+	e.direction = d.Normalize()
+}
+
+//Magnitude retourne l'intensité de la force
+func (e *AreaEffector) Magnitude() float64 {
+	return e.magnitude
+}
+
+//SetMagnitude mets l'intensité de la force à m
+func (e *AreaEffector) SetMagnitude(m float64) {
+	e.magnitude = m
+}
+
+//Falloff retourne l'atténuation
+func (e *AreaEffector) Falloff() float64 {
+	return e.falloff
+}
+
+//SetFalloff mets l'atténuation à f, entre 0 et 1
+// L'intensité décroît linéairement du centre de la région, où elle est entière,
+// jusqu'au bord, où elle est multipliée par 1 - f
+func (e *AreaEffector) SetFalloff(f float64) {
+	e.falloff = Clamp(f, 0, 1)
+}
+
+//Tags retourne les tags filtrés
+func (e *AreaEffector) Tags() []string {
+	return e.tags
+}
+
+//SetTags limite l'effecteur aux formes qui ont au moins un de ces tags
+// Sans tag, l'effecteur agit sur toutes les formes
+func (e *AreaEffector) SetTags(tags []string) {
+	e.tags = tags
+}
+
+//Mode retourne le mode de l'effecteur
+func (e *AreaEffector) Mode() EffectorMode {
+	return e.mode
+}
+
+//SetMode mets le mode de l'effecteur à m
+func (e *AreaEffector) SetMode(m EffectorMode) {
+	e.mode = m
+}
+
+//accepts retourne true si l'effecteur agit sur la forme
+func (e *AreaEffector) accepts(shape Shape) bool {
+	if shape == e.region || shape.IsStatic() || shape.IsKinematic() || shape.InvMass() == 0 {
+		return false
+	}
+	if len(e.tags) == 0 {
+		return true
+	}
+	for _, t := range e.tags {
+		if stringListContains(shape.Tags(), t) {
+			return true
+		}
+	}
+	return false
+}
+
+//forceOn retourne la force appliquée à shape, atténuée selon sa distance au centre
+func (e *AreaEffector) forceOn(shape Shape) Vec2 {
+	extent := Vec2{e.region.Width() / 2, e.region.Height() / 2}.Length()
+
+	attenuation := 1.0
+	if extent > 0 {
+		d := shape.Center().Distance(e.region.Center())
+		attenuation = 1 - e.falloff*Clamp(d/extent, 0, 1)
+	}
+
+	f := e.direction.Mult(e.magnitude * attenuation)
+	if e.mode == EffectorAcceleration {
+		f = f.Div(shape.InvMass())
+	}
+	return f
+}
+
+//Effectors retourne la liste des effecteurs de l'espace
+func (s *Space) Effectors() []*AreaEffector {
+	return s.effectors
+}
+
+//AddEffector ajoute un effecteur à l'espace
+func (s *Space) AddEffector(e *AreaEffector) {
+	s.effectors = append(s.effectors, e)
+}
+
+//RemoveEffector supprime un effecteur de l'espace
+func (s *Space) RemoveEffector(e *AreaEffector) {
+	for i, effector := range s.effectors {
+		if effector == e {
+			copy(s.effectors[i:], s.effectors[i+1:])
+			s.effectors[len(s.effectors)-1] = nil
+			s.effectors = s.effectors[:len(s.effectors)-1]
+			return
+		}
+	}
+}
+
+//applyEffectors applique la force de chaque effecteur aux formes qui chevauchent sa région
+func (s *Space) applyEffectors() {
+	for _, e := range s.effectors {
+		for _, shape := range s.shapesList {
+			if !e.accepts(shape) {
+				continue
+			}
+			if s.dispatchCollisionCheck(e.region, shape).IsColliding() {
+				shape.ApplyForce(e.forceOn(shape))
+			}
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package physics
+
+import "math"
+
+//FluidVolume volume de fluide rectangulaire qui fait flotter les formes
+// La poussée d'Archimède est proportionnelle à l'aire immergée, calculée
+// exactement pour les rectangles et les cercles. Les formes ne tournent pas:
+// seule la traînée linéaire s'applique
+type FluidVolume struct {
+	region  *Rectangle
+	density float64
+	drag    float64
+}
+
+//NewFluidVolume crée un volume de fluide de densité density sur la région region
+func NewFluidVolume(region *Rectangle, density float64) *FluidVolume {
+	return &FluidVolume{region: region, density: density}
+}
+
+//Region retourne la région du fluide
+func (f *FluidVolume) Region() *Rectangle {
+	return f.region
+}
+
+//Density retourne la densité du fluide
+func (f *FluidVolume) Density() float64 {
+	return f.density
+}
+
+//SetDensity mets la densité du fluide à d
+func (f *FluidVolume) SetDensity(d float64) {
+	f.density = d
+}
+
+//Drag retourne le coefficient de traînée
+func (f *FluidVolume) Drag() float64 {
+	return f.drag
+}
+
+//SetDrag mets le coefficient de traînée à d
+// Une forme entièrement immergée perd la fraction 1 - exp(-d) de sa vitesse par tick
+func (f *FluidVolume) SetDrag(d float64) {
+	f.drag = d
+}
+
+//SubmergedArea retourne l'aire de la forme immergée dans le fluide
+func (f *FluidVolume) SubmergedArea(shape Shape) float64 {
+	switch shape.ShapeName() {
+	case "Rectangle":
+		return rectOverlapArea(shape.Pos(), shape.Pos().Add(Vec2{shape.Width(), shape.Height()}),
+			f.region.Pos(), f.region.getMax())
+	case "Circle":
+		c := shape.(*Circle)
+		return circleRectArea(c.Center(), c.Radius(), f.region.Pos(), f.region.getMax())
+	}
+	return 0
+}
+
+//applyTo applique la poussée et la traînée du fluide à la forme
+func (f *FluidVolume) applyTo(shape Shape) {
+	submerged := f.SubmergedArea(shape)
+	if submerged <= 0 {
+		return
+	}
+
+	// poussée: poids du fluide déplacé, opposé à la gravité subie par la forme
+	shape.ApplyForce(shape.Gravity().Mult(-f.density * submerged))
+
+	if f.drag > 0 {
+		fraction := Min(1, submerged/(shape.Width()*shape.Height()*shapeFill(shape)))
+		v := shape.Velocity()
+		dv := v.Mult(math.Exp(-f.drag*fraction) - 1)
+		shape.ApplyForce(dv.Div(shape.InvMass()))
+	}
+}
+
+//shapeFill retourne la part de la boîte englobante occupée par la forme
+func shapeFill(shape Shape) float64 {
+	if shape.ShapeName() == "Circle" {
+		return math.Pi / 4
+	}
+	return 1
+}
+
+//rectOverlapArea retourne l'aire commune à deux rectangles donnés par leurs coins
+func rectOverlapArea(min1 Vec2, max1 Vec2, min2 Vec2, max2 Vec2) float64 {
+	w := Min(max1.X, max2.X) - Max(min1.X, min2.X)
+	h := Min(max1.Y, max2.Y) - Max(min1.Y, min2.Y)
+	if w <= 0 || h <= 0 {
+		return 0
+	}
+	return w * h
+}
+
+//circleRectArea retourne l'aire exacte commune à un cercle et un rectangle
+// Par inclusion-exclusion des quadrants {x < a, y < b}
+func circleRectArea(center Vec2, r float64, min Vec2, max Vec2) float64 {
+	x0, x1 := min.X-center.X, max.X-center.X
+	y0, y1 := min.Y-center.Y, max.Y-center.Y
+
+	area := circleQuadrantArea(x1, y1, r) - circleQuadrantArea(x0, y1, r) -
+		circleQuadrantArea(x1, y0, r) + circleQuadrantArea(x0, y0, r)
+	return Max(0, area)
+}
+
+//circleQuadrantArea retourne l'aire du cercle de rayon r centré à l'origine
+// contenue dans le quadrant {x < a, y < b}
+func circleQuadrantArea(a float64, b float64, r float64) float64 {
+	if a <= -r || b <= -r {
+		return 0
+	}
+	a = Min(a, r)
+
+	// primitive de la demi-corde s(x) = sqrt(r² - x²)
+	chord := func(x float64) float64 {
+		x = Clamp(x, -r, r)
+		return (x*math.Sqrt(r*r-x*x) + r*r*math.Asin(x/r)) / 2
+	}
+
+	// la hauteur de la tranche en x vaut max(0, min(b, s(x)) + s(x))
+	if b >= r {
+		return 2 * (chord(a) - chord(-r))
+	}
+
+	t := math.Sqrt(r*r - b*b)
+	area := 0.0
+
+	// |x| < t: la tranche est coupée par y = b, hauteur b + s(x)
+	if lo, hi := -t, Min(a, t); hi > lo {
+		area += b*(hi-lo) + chord(hi) - chord(lo)
+	}
+
+	// |x| >= t: la tranche est entière si b >= 0, vide sinon
+	if b >= 0 {
+		area += 2 * (chord(Min(a, -t)) - chord(-r))
+		if a > t {
+			area += 2 * (chord(a) - chord(t))
+		}
+	}
+	return area
+}
+
+//FluidVolumes retourne la liste des volumes de fluide de l'espace
+func (s *Space) FluidVolumes() []*FluidVolume {
+	return s.fluids
+}
+
+//AddFluidVolume ajoute un volume de fluide à l'espace
+func (s *Space) AddFluidVolume(f *FluidVolume) {
+	s.fluids = append(s.fluids, f)
+}
+
+//RemoveFluidVolume supprime un volume de fluide de l'espace
+func (s *Space) RemoveFluidVolume(f *FluidVolume) {
+	for i, fluid := range s.fluids {
+		if fluid == f {
+			copy(s.fluids[i:], s.fluids[i+1:])
+			s.fluids[len(s.fluids)-1] = nil
+			s.fluids = s.fluids[:len(s.fluids)-1]
+			return
+		}
+	}
+}
+
+//applyFluids applique poussée et traînée des fluides aux formes dynamiques
+func (s *Space) applyFluids() {
+	for _, f := range s.fluids {
+		for _, shape := range s.shapesList {
+			if shape == Shape(f.region) || shape.IsStatic() || shape.IsKinematic() || shape.InvMass() == 0 {
+				continue
+			}
+			f.applyTo(shape)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package physics
+
+//AttractorFalloff détermine comment l'attraction décroît avec la distance
+type AttractorFalloff int
+
+const (
+	//FalloffInverseSquare attraction en strength / distance²
+	FalloffInverseSquare AttractorFalloff = iota
+	//FalloffConstant attraction constante dans le rayon d'action
+	FalloffConstant
+)
+
+//Attractor source de gravité ponctuelle, par exemple une planète
+// Le centre est fixe, ou suit une forme si elle est donnée par SetBody
+type Attractor struct {
+	center   Vec2
+	body     Shape
+	strength float64
+	radius   float64
+	falloff  AttractorFalloff
+}
+
+//NewAttractor crée une source de gravité en center, d'intensité strength
+// et de rayon d'action radius (0 pour une portée infinie)
+func NewAttractor(center Vec2, strength float64, radius float64) *Attractor {
+	return &Attractor{center: center, strength: strength, radius: radius}
+}
+
+//Center retourne le centre d'attraction
+func (a *Attractor) Center() Vec2 {
+	if a.body != nil {
+		return a.body.Center()
+	}
+	return a.center
+}
+
+//SetCenter mets le centre d'attraction à c
+func (a *Attractor) SetCenter(c Vec2) {
+	a.center = c
+}
+
+//Body retourne la forme que suit l'attracteur, nil si fixe
+func (a *Attractor) Body() Shape {
+	return a.body
+}
+
+//SetBody fait suivre la forme b à l'attracteur. La forme n'est pas attirée par elle-même
+func (a *Attractor) SetBody(b Shape) {
+	a.body = b
+}
+
+//Strength retourne l'intensité
+func (a *Attractor) Strength() float64 {
+	return a.strength
+}
+
+//SetStrength mets l'intensité à s
+func (a *Attractor) SetStrength(s float64) {
+	a.strength = s
+}
+
+//Radius retourne le rayon d'action
+func (a *Attractor) Radius() float64 {
+	return a.radius
+}
+
+//SetRadius mets le rayon d'action à r, 0 pour une portée infinie
+func (a *Attractor) SetRadius(r float64) {
+	a.radius = r
+}
+
+//Falloff retourne le mode de décroissance
+func (a *Attractor) Falloff() AttractorFalloff {
+	return a.falloff
+}
+
+//SetFalloff mets le mode de décroissance à f
+func (a *Attractor) SetFalloff(f AttractorFalloff) {
+	a.falloff = f
+}
+
+//AccelAt retourne l'accélération due à l'attracteur au point p
+func (a *Attractor) AccelAt(p Vec2) Vec2 {
+	d := a.Center().Sub(p)
+	dist2 := d.DotProduct(d)
+	if dist2 == 0 || (a.radius > 0 && dist2 > a.radius*a.radius) {
+		return Vec2{}
+	}
+
+	dir := d.Normalize()
+	if a.falloff == FalloffConstant {
+		return dir.Mult(a.strength)
+	}
+	// évite la singularité au centre
+	return dir.Mult(a.strength / Max(dist2, 1))
+}
+
+//Attractors retourne la liste des attracteurs de l'espace
+func (s *Space) Attractors() []*Attractor {
+	return s.attractors
+}
+
+//AddAttractor ajoute un attracteur à l'espace
+func (s *Space) AddAttractor(a *Attractor) {
+	s.attractors = append(s.attractors, a)
+}
+
+//RemoveAttractor supprime un attracteur de l'espace
+func (s *Space) RemoveAttractor(a *Attractor) {
+	for i, attractor := range s.attractors {
+		if attractor == a {
+			copy(s.attractors[i:], s.attractors[i+1:])
+			s.attractors[len(s.attractors)-1] = nil
+			s.attractors = s.attractors[:len(s.attractors)-1]
+			return
+		}
+	}
+}
+
+//GravityAt retourne la gravité subie par shape: gravité de l'espace plus attractions,
+// multipliée par le facteur de gravité de la forme, ou sa propre gravité si elle en a une
+func (s *Space) GravityAt(shape Shape) Vec2 {
+	if !shape.UsesSpaceGravity() {
+		return shape.Gravity()
+	}
+	return s.gravityAt(shape.Center(), shape).Mult(shape.GravityScale())
+}
+
+//gravityAt retourne la gravité de l'espace et des attracteurs au point p,
+// sans l'attracteur porté par exclude
+func (s *Space) gravityAt(p Vec2, exclude Shape) Vec2 {
+	g := s.gravity
+	for _, a := range s.attractors {
+		if exclude == nil || a.body != exclude {
+			g = g.Add(a.AccelAt(p))
+		}
+	}
+	return g
+}
+
+//upDirection retourne le "haut" local de la forme, opposé à sa gravité
+// Sans gravité, le haut est -Y, comme à l'écran
+func upDirection(shape Shape) Vec2 {
+	g := shape.Gravity()
+	if g == (Vec2{}) {
+		return Vec2{0, -1}
+	}
+	return g.Normalize().Neg()
+}
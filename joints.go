@@ -0,0 +1,609 @@
+package physics
+
+import "math"
+
+//Joint contrainte liant deux formes, ou une forme et un point du monde
+// Second() retourne nil si la contrainte est attachée au monde
+type Joint interface {
+	First() Shape
+	Second() Shape
+	SolveVelocity()
+	SolvePosition()
+}
+
+//DistanceJoint maintient la distance entre deux ancres dans l'intervalle min - max
+// Les ancres sont exprimées relativement au centre de chaque forme.
+// Si la seconde forme est nil, la seconde ancre est un point du monde
+type DistanceJoint struct {
+	first   Shape
+	second  Shape
+	anchor1 Vec2
+	anchor2 Vec2
+	min     float64
+	max     float64
+	// impulsion accumulée pendant le dernier pas, positive si étirée
+	impulse      float64
+	breakTension float64
+	broken       bool
+}
+
+//NewDistanceJoint crée une contrainte de distance entre deux formes
+// La distance est fixée à la distance actuelle entre les deux ancres
+func NewDistanceJoint(first Shape, second Shape, anchor1 Vec2, anchor2 Vec2) *DistanceJoint {
+	j := &DistanceJoint{first: first, second: second, anchor1: anchor1, anchor2: anchor2}
+	d := anchorPoint(first, anchor1).Distance(anchorPoint(second, anchor2))
+	j.SetLimits(d, d)
+	return j
+}
+
+//First retourne la première forme
+func (j *DistanceJoint) First() Shape {
+	return j.first
+}
+
+//Second retourne la seconde forme, nil si attachée au monde
+func (j *DistanceJoint) Second() Shape {
+	return j.second
+}
+
+//Limits retourne les distances minimale et maximale
+func (j *DistanceJoint) Limits() (float64, float64) {
+	return j.min, j.max
+}
+
+//SetLimits mets les distances minimale et maximale
+// min == max donne une distance fixe
+func (j *DistanceJoint) SetLimits(min float64, max float64) {
+	j.min = Max(0, Min(min, max))
+	j.max = Max(min, max)
+	wakeJoint(j)
+}
+
+//SetLength fixe la distance entre les ancres à l
+func (j *DistanceJoint) SetLength(l float64) {
+	j.SetLimits(l, l)
+}
+
+//Tension retourne l'impulsion appliquée par la contrainte au dernier pas
+// Positive si la contrainte retient les ancres, négative si elle les écarte
+func (j *DistanceJoint) Tension() float64 {
+	return j.impulse
+}
+
+//SetBreakTension mets la tension de rupture à t, 0 pour une contrainte incassable
+func (j *DistanceJoint) SetBreakTension(t float64) {
+	j.breakTension = t
+}
+
+//Broken retourne true si la contrainte a rompu
+// L'espace supprime les contraintes rompues
+func (j *DistanceJoint) Broken() bool {
+	return j.broken
+}
+
+//SolveVelocity remet la tension à zéro et fait une première passe sur les vitesses
+// L'espace refait ensuite des passes, voir relaxVelocity
+func (j *DistanceJoint) SolveVelocity() {
+	j.impulse = 0
+	j.relaxVelocity()
+}
+
+//relaxVelocity corrige la vitesse relative le long de la contrainte pour que
+// les ancres arrivent à la limite de distance à la fin du pas, sans la dépasser.
+// L'impulsion suit l'axe actuel des ancres: elle ne travaille pas le long du
+// mouvement, un pendule remonte à sa hauteur de départ
+func (j *DistanceJoint) relaxVelocity() {
+	p1 := anchorPoint(j.first, j.anchor1)
+	p2 := anchorPoint(j.second, j.anchor2)
+
+	d := p2.Sub(p1)
+	dist := d.Length()
+
+	im1, im2 := jointInvMass(j.first), jointInvMass(j.second)
+	totInvMass := im1 + im2
+	if j.broken || dist == 0 || totInvMass == 0 {
+		return
+	}
+
+	// écart des ancres à la fin du pas, sans la contrainte
+	w := d.Add(predictedVelocity(j.second).Sub(predictedVelocity(j.first)))
+	next := w.Length()
+
+	if next >= j.min && next <= j.max {
+		return
+	}
+	target := Clamp(next, j.min, j.max)
+
+	// dv le long de n tel que |w + dv*n| = target, racine la plus proche de 0
+	n := d.Div(dist)
+	wn := w.DotProduct(n)
+	dv := target - wn
+	if disc := wn*wn - next*next + target*target; disc >= 0 {
+		dv = -wn + math.Sqrt(disc)
+		if wn < 0 {
+			dv = -wn - math.Sqrt(disc)
+		}
+	}
+
+	pushVelocity(j.first, n.Mult(-dv*im1/totInvMass))
+	pushVelocity(j.second, n.Mult(dv*im2/totInvMass))
+	j.impulse -= dv / totInvMass
+}
+
+//SolvePosition rompt la contrainte si la tension du pas dépasse la tension de rupture,
+// puis corrige la dérive des ancres hors de l'intervalle sans toucher aux vitesses
+func (j *DistanceJoint) SolvePosition() {
+	if j.breakTension > 0 && Abs(j.impulse) > j.breakTension {
+		j.broken = true
+	}
+
+	p1 := anchorPoint(j.first, j.anchor1)
+	p2 := anchorPoint(j.second, j.anchor2)
+
+	d := p2.Sub(p1)
+	dist := d.Length()
+
+	if j.broken || dist == 0 || (dist >= j.min && dist <= j.max) {
+		return
+	}
+
+	n := d.Div(dist)
+	target := Clamp(dist, j.min, j.max)
+	moveOffset(j.first, j.second, n.Mult(dist-target))
+}
+
+//correctOffset déplace first et second pour annuler l'écart e entre leurs ancres
+// (e va de l'ancre de first vers celle de second), proportionnellement aux masses inverses.
+// La vitesse reçoit la même correction, pour que la forme ne reparte
+// pas au pas suivant dans la direction qui viole la contrainte.
+// Retourne l'impulsion appliquée
+func correctOffset(first Shape, second Shape, e Vec2) Vec2 {
+	corr1, corr2 := moveOffset(first, second, e)
+	pushVelocity(first, corr1)
+	pushVelocity(second, corr2)
+
+	totInvMass := jointInvMass(first) + jointInvMass(second)
+	if totInvMass == 0 {
+		return Vec2{}
+	}
+	return e.Div(totInvMass)
+}
+
+//moveOffset déplace first et second pour annuler l'écart e entre leurs ancres,
+// proportionnellement aux masses inverses, et retourne le déplacement de chacune
+func moveOffset(first Shape, second Shape, e Vec2) (Vec2, Vec2) {
+	im1, im2 := jointInvMass(first), jointInvMass(second)
+	totInvMass := im1 + im2
+	if totInvMass == 0 {
+		return Vec2{}, Vec2{}
+	}
+
+	corr1 := e.Mult(im1 / totInvMass)
+	corr2 := e.Mult(-im2 / totInvMass)
+	moveBy(first, corr1)
+	moveBy(second, corr2)
+	return corr1, corr2
+}
+
+//anchorPoint retourne la position dans le monde d'une ancre
+// Si la forme est nil, l'ancre est déjà un point du monde
+func anchorPoint(s Shape, anchor Vec2) Vec2 {
+	if s == nil {
+		return anchor
+	}
+	return s.Center().Add(anchor)
+}
+
+//localAnchor retourne l'ancre relative à la forme correspondant au point p du monde
+// Si la forme est nil, p est retourné tel quel
+func localAnchor(s Shape, p Vec2) Vec2 {
+	if s == nil {
+		return p
+	}
+	return p.Sub(s.Center())
+}
+
+//jointInvMass retourne la masse inverse d'une forme liée, 0 si immobile
+func jointInvMass(s Shape) float64 {
+	if s == nil || s.IsStatic() {
+		return 0
+	}
+	return s.InvMass()
+}
+
+//jointVelocity retourne la vitesse d'une forme liée, 0 si attachée au monde
+func jointVelocity(s Shape) Vec2 {
+	if s == nil {
+		return Vec2{}
+	}
+	return s.Velocity()
+}
+
+//predictedVelocity retourne la vitesse qu'aura la forme après ajout
+// de l'accélération, de la gravité et des forces accumulées
+func predictedVelocity(s Shape) Vec2 {
+	if jointInvMass(s) == 0 {
+		return jointVelocity(s)
+	}
+	return s.Velocity().Add(s.Accel()).Add(s.Gravity()).Add(s.Force().Mult(s.InvMass()))
+}
+
+//moveBy déplace la forme de d si elle est mobile
+func moveBy(s Shape, d Vec2) {
+	if jointInvMass(s) == 0 {
+		return
+	}
+	s.SetPos(s.Pos().Add(d))
+}
+
+//pushVelocity ajoute dv à la vitesse de la forme si elle est mobile
+func pushVelocity(s Shape, dv Vec2) {
+	if jointInvMass(s) == 0 {
+		return
+	}
+	s.SetVelocity(s.Velocity().Add(dv))
+}
+
+//DampedSpring ressort amorti entre deux ancres, ou entre une ancre et un point du monde
+// La raideur et l'amortissement sont exprimés par tick, comme les vitesses.
+// Tant qu'il oscille en plus de 2π ticks, seul l'amortissement le freine
+type DampedSpring struct {
+	first      Shape
+	second     Shape
+	anchor1    Vec2
+	anchor2    Vec2
+	restLength float64
+	stiffness  float64
+	damping    float64
+}
+
+//maxSpringOmega pulsation maximale, en radians par tick, d'un ressort intégré exactement
+// Au-delà, le tick est trop long pour suivre l'oscillation
+const maxSpringOmega = 1.0
+
+//NewDampedSpring crée un ressort entre deux formes
+// Si second est nil, anchor2 est un point du monde
+func NewDampedSpring(first Shape, second Shape, anchor1 Vec2, anchor2 Vec2,
+	restLength float64, stiffness float64, damping float64) *DampedSpring {
+	return &DampedSpring{
+		first:      first,
+		second:     second,
+		anchor1:    anchor1,
+		anchor2:    anchor2,
+		restLength: restLength,
+		stiffness:  stiffness,
+		damping:    damping,
+	}
+}
+
+//First retourne la première forme
+func (sp *DampedSpring) First() Shape {
+	return sp.first
+}
+
+//Second retourne la seconde forme, nil si attachée au monde
+func (sp *DampedSpring) Second() Shape {
+	return sp.second
+}
+
+//RestLength retourne la longueur au repos
+func (sp *DampedSpring) RestLength() float64 {
+	return sp.restLength
+}
+
+//SetRestLength mets la longueur au repos à l
+func (sp *DampedSpring) SetRestLength(l float64) {
+	sp.restLength = l
+	wakeJoint(sp)
+}
+
+//Stiffness retourne la raideur
+func (sp *DampedSpring) Stiffness() float64 {
+	return sp.stiffness
+}
+
+//SetStiffness mets la raideur à k
+func (sp *DampedSpring) SetStiffness(k float64) {
+	sp.stiffness = k
+	wakeJoint(sp)
+}
+
+//Damping retourne l'amortissement
+func (sp *DampedSpring) Damping() float64 {
+	return sp.damping
+}
+
+//SetDamping mets l'amortissement à c
+// Un ressort trop raide pour le tick (voir maxSpringOmega) est en plus amorti
+// par l'intégration: il revient au repos en quelques ticks même avec c à 0
+func (sp *DampedSpring) SetDamping(c float64) {
+	sp.damping = c
+}
+
+//SolveVelocity applique l'impulsion du ressort
+// L'impulsion est choisie pour que le mouvement relatif le long du ressort suive,
+// d'un tick à l'autre, la solution exacte de l'oscillateur amorti (voir springCoefficients)
+func (sp *DampedSpring) SolveVelocity() {
+	p1 := anchorPoint(sp.first, sp.anchor1)
+	p2 := anchorPoint(sp.second, sp.anchor2)
+
+	d := p2.Sub(p1)
+	dist := d.Length()
+	if dist == 0 {
+		return
+	}
+
+	im1, im2 := jointInvMass(sp.first), jointInvMass(sp.second)
+	totInvMass := im1 + im2
+	if totInvMass == 0 {
+		return
+	}
+
+	n := d.Div(dist)
+	x := dist - sp.restLength
+	vRel := predictedVelocity(sp.second).Sub(predictedVelocity(sp.first)).DotProduct(n)
+
+	// comprimé, le ressort pousse aussi en travers avec une raideur k*(restLength/dist - 1)
+	stiffest := sp.stiffness * totInvMass * Max(1, sp.restLength/dist-1)
+	kk, cc := springCoefficients(sp.stiffness*totInvMass, sp.damping*totInvMass, stiffest)
+	impulse := -(kk*x + cc*vRel) / totInvMass
+
+	pushVelocity(sp.first, n.Mult(-impulse*im1))
+	pushVelocity(sp.second, n.Mult(impulse*im2))
+}
+
+//springCoefficients retourne les coefficients kk et cc de l'impulsion d'un ressort,
+// v' = v - kk*x - cc*v puis x' = x + v', pour la raideur k et l'amortissement c
+// ramenés à la masse inverse. stiffest est la plus grande raideur du ressort, en travers
+// compris. Tant que sa pulsation ne dépasse pas maxSpringOmega, la trace et le déterminant
+// de l'application sont ceux de la solution exacte sur un tick:
+// det = exp(-c), trace = 2*exp(-c/2)*cos(wd), wd² = k - (c/2)².
+// Au-delà, Euler implicite reste stable quand plusieurs ressorts raides
+// se partagent une forme, au prix d'un amortissement en plus
+func springCoefficients(k float64, c float64, stiffest float64) (float64, float64) {
+	if stiffest > maxSpringOmega*maxSpringOmega {
+		return k / (1 + c + k), (c + k) / (1 + c + k)
+	}
+
+	decay := math.Exp(-c / 2)
+	wd2 := k - c*c/4
+	osc := math.Cosh(math.Sqrt(-wd2))
+	if wd2 > 0 {
+		osc = math.Cos(math.Sqrt(wd2))
+	}
+	cc := 1 - decay*decay
+	return 2 - cc - 2*decay*osc, cc
+}
+
+//SolvePosition ne fait rien: le ressort agit sur les vitesses
+func (sp *DampedSpring) SolvePosition() {}
+
+//PinJoint fait coïncider deux ancres, une sur chaque forme
+// Si second est nil, anchor2 est un point du monde. Les formes n'ont pas d'orientation:
+// la liaison fixe leur position relative et sert aussi de soudure (WeldJoint)
+type PinJoint struct {
+	first   Shape
+	second  Shape
+	anchor1 Vec2
+	anchor2 Vec2
+}
+
+//NewPinJoint crée une liaison entre deux formes au point pivot (coordonnées monde)
+// Si second est nil, la forme est accrochée à un point fixe du monde
+func NewPinJoint(first Shape, second Shape, pivot Vec2) *PinJoint {
+	return &PinJoint{
+		first:   first,
+		second:  second,
+		anchor1: localAnchor(first, pivot),
+		anchor2: localAnchor(second, pivot),
+	}
+}
+
+//First retourne la première forme
+func (j *PinJoint) First() Shape {
+	return j.first
+}
+
+//Second retourne la seconde forme, nil si attachée au monde
+func (j *PinJoint) Second() Shape {
+	return j.second
+}
+
+//SolveVelocity ne fait rien: la contrainte est résolue en position
+func (j *PinJoint) SolveVelocity() {}
+
+//SolvePosition ramène les deux ancres l'une sur l'autre
+func (j *PinJoint) SolvePosition() {
+	e := anchorPoint(j.second, j.anchor2).Sub(anchorPoint(j.first, j.anchor1))
+	correctOffset(j.first, j.second, e)
+}
+
+//SliderJoint limite le mouvement relatif de deux formes à un axe fixe
+// La translation le long de l'axe peut être bornée par SetLimits
+type SliderJoint struct {
+	first       Shape
+	second      Shape
+	anchor1     Vec2
+	anchor2     Vec2
+	axis        Vec2
+	lower       float64
+	upper       float64
+	limitActive bool
+}
+
+//NewSliderJoint crée une liaison glissière entre deux formes le long de axis
+// La translation est mesurée depuis la position actuelle. Si second est nil,
+// first glisse le long d'un rail fixe du monde
+func NewSliderJoint(first Shape, second Shape, axis Vec2) *SliderJoint {
+	origin := first.Center()
+	return &SliderJoint{
+		first:   first,
+		second:  second,
+		anchor1: Vec2{},
+		anchor2: localAnchor(second, origin),
+		axis:    axis.Normalize(),
+	}
+}
+
+//First retourne la première forme
+func (j *SliderJoint) First() Shape {
+	return j.first
+}
+
+//Second retourne la seconde forme, nil si attachée au monde
+func (j *SliderJoint) Second() Shape {
+	return j.second
+}
+
+//Axis retourne l'axe (normalisé) de la glissière
+func (j *SliderJoint) Axis() Vec2 {
+	return j.axis
+}
+
+//Translation retourne le déplacement de first le long de l'axe
+// depuis la création de la liaison
+func (j *SliderJoint) Translation() float64 {
+	return anchorPoint(j.first, j.anchor1).Sub(anchorPoint(j.second, j.anchor2)).DotProduct(j.axis)
+}
+
+//SetLimits borne la translation entre lower et upper
+func (j *SliderJoint) SetLimits(lower float64, upper float64) {
+	j.lower = Min(lower, upper)
+	j.upper = Max(lower, upper)
+	j.limitActive = true
+	wakeJoint(j)
+}
+
+//ClearLimits supprime les bornes de translation
+func (j *SliderJoint) ClearLimits() {
+	j.limitActive = false
+	wakeJoint(j)
+}
+
+//SolveVelocity ne fait rien: la contrainte est résolue en position
+func (j *SliderJoint) SolveVelocity() {}
+
+//SolvePosition annule l'écart perpendiculaire à l'axe
+// et ramène la translation dans les bornes
+func (j *SliderJoint) SolvePosition() {
+	d := anchorPoint(j.second, j.anchor2).Sub(anchorPoint(j.first, j.anchor1))
+	along := d.DotProduct(j.axis)
+
+	// écart perpendiculaire à l'axe
+	e := d.Sub(j.axis.Mult(along))
+
+	if j.limitActive {
+		// d va de first vers l'origine du rail: la translation vaut -along
+		translation := -along
+		excess := translation - Clamp(translation, j.lower, j.upper)
+		e = e.Sub(j.axis.Mult(excess))
+	}
+
+	correctOffset(j.first, j.second, e)
+}
+
+//WeldJoint soude deux formes: leur position relative reste celle de la création
+// C'est une liaison PinJoint dont le point commun est le centre de first
+type WeldJoint = PinJoint
+
+//NewWeldJoint crée une soudure entre deux formes
+// Si second est nil, first est soudée à sa position actuelle dans le monde
+func NewWeldJoint(first Shape, second Shape) *WeldJoint {
+	return NewPinJoint(first, second, first.Center())
+}
+
+//TargetJoint attire un point d'une forme vers une cible mobile, typiquement la souris
+// La contrainte agit sur la vitesse: les collisions continuent de s'appliquer
+// et la force est bornée, la forme ne traverse donc pas les murs
+type TargetJoint struct {
+	shape    Shape
+	anchor   Vec2
+	target   Vec2
+	maxForce float64
+	softness float64
+}
+
+//NewTargetJoint crée une contrainte qui attire la forme par le point grab (coordonnées monde)
+// maxForce borne l'impulsion appliquée à chaque tick, 0 pour ne pas la borner.
+// Sans borne, la vitesse reste limitée pour que la forme ne traverse pas les murs
+func NewTargetJoint(shape Shape, grab Vec2, maxForce float64) *TargetJoint {
+	return &TargetJoint{
+		shape:    shape,
+		anchor:   localAnchor(shape, grab),
+		target:   grab,
+		maxForce: maxForce,
+	}
+}
+
+//First retourne la forme attirée
+func (j *TargetJoint) First() Shape {
+	return j.shape
+}
+
+//Second retourne nil: la cible est un point du monde
+func (j *TargetJoint) Second() Shape {
+	return nil
+}
+
+//Target retourne la cible
+func (j *TargetJoint) Target() Vec2 {
+	return j.target
+}
+
+//SetTarget mets la cible à t et réveille la forme si la cible a bougé
+func (j *TargetJoint) SetTarget(t Vec2) {
+	if t != j.target {
+		j.target = t
+		wakeJoint(j)
+	}
+}
+
+//MaxForce retourne l'impulsion maximale par tick
+func (j *TargetJoint) MaxForce() float64 {
+	return j.maxForce
+}
+
+//SetMaxForce mets l'impulsion maximale par tick à f, 0 pour ne pas la borner
+func (j *TargetJoint) SetMaxForce(f float64) {
+	j.maxForce = f
+	wakeJoint(j)
+}
+
+//Softness retourne la souplesse
+func (j *TargetJoint) Softness() float64 {
+	return j.softness
+}
+
+//SetSoftness mets la souplesse à s, entre 0 (la cible est atteinte en un tick)
+// et 1 (la forme n'est plus attirée)
+func (j *TargetJoint) SetSoftness(s float64) {
+	j.softness = Clamp(s, 0, 1)
+	wakeJoint(j)
+}
+
+//SolveVelocity donne à la forme la vitesse qui la rapproche de la cible
+// en compensant la gravité, dans la limite de maxForce. La vitesse est bornée au quart
+// de la plus petite dimension de la forme par tick: la collision ne corrige qu'une part
+// de l'enfoncement, et une cible lointaine lui ferait traverser un mur, même sans maxForce
+func (j *TargetJoint) SolveVelocity() {
+	im := jointInvMass(j.shape)
+	if im == 0 {
+		return
+	}
+
+	e := j.target.Sub(anchorPoint(j.shape, j.anchor))
+	desired := e.Mult(1 - j.softness)
+	if maxSpeed := Min(j.shape.Width(), j.shape.Height()) / 4; desired.Length() > maxSpeed {
+		desired = desired.Normalize().Mult(maxSpeed)
+	}
+
+	impulse := desired.Sub(predictedVelocity(j.shape)).Div(im)
+	if l := impulse.Length(); j.maxForce > 0 && l > j.maxForce {
+		impulse = impulse.Mult(j.maxForce / l)
+	}
+
+	pushVelocity(j.shape, impulse.Mult(im))
+}
+
+//SolvePosition ne fait rien: la contrainte agit sur les vitesses
+func (j *TargetJoint) SolvePosition() {}
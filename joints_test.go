@@ -0,0 +1,51 @@
+package physics
+
+import "testing"
+
+func TestDistanceJointPendulumKeepsEnergy(t *testing.T) {
+	s := &Space{}
+	s.SetGravity(Vec2{0, 0.5})
+	pivot := Vec2{200, 100}
+	bob := NewCircle(Vec2{300, 100}, 5)
+	s.AddShape(bob)
+	s.AddJoint(NewDistanceJoint(bob, nil, Vec2{}, pivot))
+
+	// lâché à l'horizontale, le pendule doit remonter à la hauteur du pivot à chaque oscillation
+	top := bob.Center().Y
+	for tick := 1; tick <= 2000; tick++ {
+		s.Update()
+		c := bob.Center()
+		if l := c.Distance(pivot); Abs(l-100) > 0.01 {
+			t.Fatalf("tick %d: rod length %v, want 100", tick, l)
+		}
+		top = Min(top, c.Y)
+		if tick%500 == 0 {
+			if top > 101 {
+				t.Fatalf("ticks %d-%d: pendulum only climbed back to y %v, want 100", tick-499, tick, top)
+			}
+			top = c.Y
+		}
+	}
+}
+
+func TestDampedSpringKeepsAmplitude(t *testing.T) {
+	for _, k := range []float64{5, 50} {
+		s := &Space{}
+		ball := NewCircle(Vec2{150, 0}, 5)
+		s.AddShape(ball)
+		anchor := Vec2{}
+		s.AddJoint(NewDampedSpring(ball, nil, Vec2{}, anchor, 100, k, 0))
+
+		// étiré de 50 et lâché: sans amortissement le ressort repasse par 150 à chaque oscillation
+		peak := 0.0
+		for tick := 1; tick <= 400; tick++ {
+			s.Update()
+			if tick > 200 {
+				peak = Max(peak, ball.Center().Distance(anchor))
+			}
+		}
+		if peak < 148 {
+			t.Errorf("k %v: peak length %v after 200 ticks, want 150", k, peak)
+		}
+	}
+}
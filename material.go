@@ -0,0 +1,157 @@
+package physics
+
+import "fmt"
+
+//Material propriétés de surface partagées par plusieurs formes
+type Material struct {
+	Name        string
+	Restitution float64
+	Friction    float64
+}
+
+//CombineMode règle de combinaison des coefficients de deux formes en contact
+type CombineMode int
+
+const (
+	//CombineMin retient le plus petit des deux coefficients
+	CombineMin CombineMode = iota
+	//CombineMax retient le plus grand des deux coefficients
+	CombineMax
+	//CombineAverage retient la moyenne des deux coefficients
+	CombineAverage
+	//CombineMultiply retient le produit des deux coefficients
+	CombineMultiply
+)
+
+//combine applique la règle à a et b
+func (m CombineMode) combine(a float64, b float64) float64 {
+	switch m {
+	case CombineMax:
+		return Max(a, b)
+	case CombineAverage:
+		return (a + b) / 2
+	case CombineMultiply:
+		return a * b
+	default:
+		return Min(a, b)
+	}
+}
+
+//materialPair clé de la table des paires, indépendante de l'ordre
+type materialPair [2]string
+
+func newMaterialPair(a string, b string) materialPair {
+	if a > b {
+		a, b = b, a
+	}
+	return materialPair{a, b}
+}
+
+//MaterialLibrary bibliothèque de matériaux nommés et règles de combinaison
+// Une paire de matériaux peut recevoir des coefficients explicites,
+// qui priment sur les règles de combinaison
+type MaterialLibrary struct {
+	materials       map[string]*Material
+	pairs           map[materialPair]Material
+	restitutionMode CombineMode
+	frictionMode    CombineMode
+}
+
+//NewMaterialLibrary crée une bibliothèque avec les matériaux ice, rubber, wood et metal
+// La restitution est combinée par CombineMin, la friction par CombineMax:
+// une forme sur un sol sans friction garde sa propre friction
+func NewMaterialLibrary() *MaterialLibrary {
+	l := &MaterialLibrary{
+		materials:       map[string]*Material{},
+		pairs:           map[materialPair]Material{},
+		restitutionMode: CombineMin,
+		frictionMode:    CombineMax,
+	}
+	l.Add(&Material{Name: "ice", Restitution: 0.05, Friction: 0.01})
+	l.Add(&Material{Name: "rubber", Restitution: 0.8, Friction: 0.3})
+	l.Add(&Material{Name: "wood", Restitution: 0.3, Friction: 0.15})
+	l.Add(&Material{Name: "metal", Restitution: 0.2, Friction: 0.08})
+	return l
+}
+
+//Add ajoute ou remplace un matériau
+func (l *MaterialLibrary) Add(m *Material) {
+	l.materials[m.Name] = m
+}
+
+//Get retourne le matériau qui a ce nom ou rien et une erreur
+func (l *MaterialLibrary) Get(name string) (*Material, error) {
+	if m, exists := l.materials[name]; exists {
+		return m, nil
+	}
+	return nil, fmt.Errorf("Pas de matériau ayant ce nom: %v", name)
+}
+
+//Materials retourne la liste des matériaux
+func (l *MaterialLibrary) Materials() []*Material {
+	materials := make([]*Material, 0, len(l.materials))
+	for _, m := range l.materials {
+		materials = append(materials, m)
+	}
+	return materials
+}
+
+//SetCombine mets les règles de combinaison de la restitution et de la friction
+func (l *MaterialLibrary) SetCombine(restitution CombineMode, friction CombineMode) {
+	l.restitutionMode = restitution
+	l.frictionMode = friction
+}
+
+//Combine retourne les règles de combinaison de la restitution et de la friction
+func (l *MaterialLibrary) Combine() (CombineMode, CombineMode) {
+	return l.restitutionMode, l.frictionMode
+}
+
+//SetPair fixe la restitution et la friction du contact entre les matériaux a et b
+func (l *MaterialLibrary) SetPair(a string, b string, restitution float64, friction float64) {
+	l.pairs[newMaterialPair(a, b)] = Material{Restitution: restitution, Friction: friction}
+}
+
+//RemovePair supprime les coefficients explicites de la paire a, b
+func (l *MaterialLibrary) RemovePair(a string, b string) {
+	delete(l.pairs, newMaterialPair(a, b))
+}
+
+//Mix retourne la restitution et la friction du contact entre deux formes
+func (l *MaterialLibrary) Mix(first Shape, second Shape) (float64, float64) {
+	if first.Material() != nil && second.Material() != nil {
+		pair := newMaterialPair(first.Material().Name, second.Material().Name)
+		if m, exists := l.pairs[pair]; exists {
+			return m.Restitution, m.Friction
+		}
+	}
+	e1, f1 := surface(first)
+	e2, f2 := surface(second)
+	return l.restitutionMode.combine(e1, e2), l.frictionMode.combine(f1, f2)
+}
+
+//surface retourne l'élasticité et la friction de la forme: celles de son matériau,
+// lues à chaque contact pour suivre ses modifications, sinon les siennes
+func surface(shape Shape) (float64, float64) {
+	if m := shape.Material(); m != nil {
+		return m.Restitution, m.Friction
+	}
+	return shape.Elasticity(), shape.Friction()
+}
+
+//defaultMaterials sert aux collisions qui ne viennent pas d'un espace
+var defaultMaterials = NewMaterialLibrary()
+
+//Materials retourne la bibliothèque de matériaux de l'espace
+func (s *Space) Materials() *MaterialLibrary {
+	if s.materials == nil {
+		s.materials = NewMaterialLibrary()
+	}
+	return s.materials
+}
+
+//SetMaterials mets la bibliothèque de matériaux de l'espace à l
+// Plusieurs espaces peuvent partager la même bibliothèque
+func (s *Space) SetMaterials(l *MaterialLibrary) {
+	s.materials = l
+}
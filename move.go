@@ -0,0 +1,88 @@
+package physics
+
+const (
+	// pénétration en dessous de laquelle un contact ne bloque pas un déplacement
+	moveSkin float64 = 1e-9
+)
+
+//MoveAndCollide déplace shape de motion et s'arrête au premier obstacle solide
+// Le déplacement est découpé en pas plus courts que la moitié de la forme pour ne
+// pas traverser les obstacles. Seuls les contacts vers lesquels la forme se déplace
+// l'arrêtent: une forme posée sur un sol peut glisser dessus ou s'en éloigner.
+// Retourne la collision, nil si aucune, et le déplacement qui reste à faire.
+// La seconde forme de la collision est shape, la normale pointe vers elle.
+// Les autres formes ne sont pas déplacées et aucune vitesse n'est modifiée
+func (s *Space) MoveAndCollide(shape Shape, motion Vec2) (*CollisionInfo, Vec2) {
+	length := motion.Length()
+	if length == 0 {
+		return nil, Vec2{}
+	}
+
+	maxStep := Min(shape.Width(), shape.Height()) / 2
+	steps := 1
+	if maxStep > 0 {
+		steps = int(length/maxStep) + 1
+	}
+	step := motion.Div(float64(steps))
+	start := shape.Pos()
+
+	for i := 0; i < steps; i++ {
+		shape.SetPos(shape.Pos().Add(step))
+
+		if info := s.blockingCollision(shape, motion); info != nil {
+			// repousse la forme hors de l'obstacle
+			shape.SetPos(shape.Pos().Add(info.normal.Mult(info.penetration)))
+			return info, motion.Sub(shape.Pos().Sub(start))
+		}
+	}
+	return nil, Vec2{}
+}
+
+//MoveAndSlide déplace shape de motion en glissant le long des obstacles
+// À chaque obstacle, le reste du déplacement est projeté sur la surface,
+// au plus maxSlides fois. Retourne les collisions rencontrées, dans l'ordre
+func (s *Space) MoveAndSlide(shape Shape, motion Vec2, maxSlides int) []*CollisionInfo {
+	hits := []*CollisionInfo{}
+
+	for i := 0; i <= maxSlides && motion != (Vec2{}); i++ {
+		info, rest := s.MoveAndCollide(shape, motion)
+		if info == nil {
+			break
+		}
+		hits = append(hits, info)
+
+		// retire la composante qui entre dans l'obstacle
+		n := info.normal
+		motion = rest.Sub(n.Mult(rest.DotProduct(n)))
+	}
+	return hits
+}
+
+//blockingCollision retourne la collision la plus profonde qui s'oppose au déplacement motion,
+// orientée pour que shape soit la seconde forme, nil si aucune
+func (s *Space) blockingCollision(shape Shape, motion Vec2) *CollisionInfo {
+	var deepest *CollisionInfo
+
+	for _, other := range s.shapesList {
+		if other == shape || !other.IsSolid() {
+			continue
+		}
+
+		info := s.dispatchCollisionCheck(other, shape)
+		if !info.IsColliding() {
+			continue
+		}
+		if info.first == shape {
+			info.first, info.second = info.second, info.first
+			info.normal = info.normal.Neg()
+		}
+
+		if info.penetration <= moveSkin || motion.DotProduct(info.normal) >= 0 {
+			continue
+		}
+		if deepest == nil || info.penetration > deepest.penetration {
+			deepest = info
+		}
+	}
+	return deepest
+}
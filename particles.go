@@ -0,0 +1,444 @@
+package physics
+
+import (
+	"math"
+	"sort"
+)
+
+//ParticleEmitter système de particules ponctuelles intégrées par Verlet
+// Les particules sont stockées en colonnes (une tranche par grandeur) plutôt
+// qu'en formes: pas de BasicShape, pas de test entre particules.
+// Elles rebondissent sur les formes solides de l'espace sans les influencer.
+// Le déplacement de chaque tick est testé comme un segment: une particule rapide
+// ne traverse pas une forme fine
+type ParticleEmitter struct {
+	x, y         []float64
+	prevX, prevY []float64
+	age          []float64
+	lifetime     float64
+	bounce       float64
+	friction     float64
+	gravScale    float64
+	maxParticles int
+}
+
+const (
+	defaultParticleLifetime float64 = 120
+	defaultParticleBounce   float64 = 0.3
+	defaultParticleFriction float64 = 0.1
+)
+
+//NewParticleEmitter crée un émetteur vide aux valeurs par défaut
+func NewParticleEmitter() *ParticleEmitter {
+	return &ParticleEmitter{
+		lifetime:  defaultParticleLifetime,
+		bounce:    defaultParticleBounce,
+		friction:  defaultParticleFriction,
+		gravScale: 1,
+	}
+}
+
+//Lifetime retourne la durée de vie des particules, en ticks
+func (e *ParticleEmitter) Lifetime() float64 {
+	return e.lifetime
+}
+
+//SetLifetime mets la durée de vie des particules, en ticks. 0 pour qu'elles ne meurent pas
+func (e *ParticleEmitter) SetLifetime(l float64) {
+	e.lifetime = l
+}
+
+//Bounce retourne la part de la vitesse normale conservée au rebond
+func (e *ParticleEmitter) Bounce() float64 {
+	return e.bounce
+}
+
+//SetBounce mets la part de la vitesse normale conservée au rebond, entre 0 et 1
+func (e *ParticleEmitter) SetBounce(b float64) {
+	e.bounce = Clamp(b, 0, 1)
+}
+
+//Friction retourne la part de la vitesse tangentielle perdue au contact
+func (e *ParticleEmitter) Friction() float64 {
+	return e.friction
+}
+
+//SetFriction mets la part de la vitesse tangentielle perdue au contact, entre 0 et 1
+func (e *ParticleEmitter) SetFriction(f float64) {
+	e.friction = Clamp(f, 0, 1)
+}
+
+//GravityScale retourne le facteur appliqué à la gravité des particules
+func (e *ParticleEmitter) GravityScale() float64 {
+	return e.gravScale
+}
+
+//SetGravityScale mets le facteur appliqué à la gravité des particules
+func (e *ParticleEmitter) SetGravityScale(g float64) {
+	e.gravScale = g
+}
+
+//MaxParticles retourne le nombre maximum de particules vivantes, 0 si illimité
+func (e *ParticleEmitter) MaxParticles() int {
+	return e.maxParticles
+}
+
+//SetMaxParticles mets le nombre maximum de particules vivantes, 0 pour illimité
+func (e *ParticleEmitter) SetMaxParticles(n int) {
+	e.maxParticles = n
+}
+
+//Emit ajoute une particule en pos avec la vitesse vel (par tick)
+// Retourne false si l'émetteur est plein
+func (e *ParticleEmitter) Emit(pos Vec2, vel Vec2) bool {
+	if e.maxParticles > 0 && len(e.x) >= e.maxParticles {
+		return false
+	}
+	e.x = append(e.x, pos.X)
+	e.y = append(e.y, pos.Y)
+	e.prevX = append(e.prevX, pos.X-vel.X)
+	e.prevY = append(e.prevY, pos.Y-vel.Y)
+	e.age = append(e.age, 0)
+	return true
+}
+
+//Count retourne le nombre de particules vivantes
+func (e *ParticleEmitter) Count() int {
+	return len(e.x)
+}
+
+//Position retourne la position de la particule i
+func (e *ParticleEmitter) Position(i int) Vec2 {
+	return Vec2{e.x[i], e.y[i]}
+}
+
+//Velocity retourne la vitesse de la particule i, par tick
+func (e *ParticleEmitter) Velocity(i int) Vec2 {
+	return Vec2{e.x[i] - e.prevX[i], e.y[i] - e.prevY[i]}
+}
+
+//Age retourne l'âge de la particule i, en ticks
+func (e *ParticleEmitter) Age(i int) float64 {
+	return e.age[i]
+}
+
+//Clear supprime toutes les particules
+func (e *ParticleEmitter) Clear() {
+	e.x, e.y = e.x[:0], e.y[:0]
+	e.prevX, e.prevY = e.prevX[:0], e.prevY[:0]
+	e.age = e.age[:0]
+}
+
+//kill supprime la particule i en la remplaçant par la dernière
+func (e *ParticleEmitter) kill(i int) {
+	last := len(e.x) - 1
+	e.x[i], e.y[i] = e.x[last], e.y[last]
+	e.prevX[i], e.prevY[i] = e.prevX[last], e.prevY[last]
+	e.age[i] = e.age[last]
+
+	e.x, e.y = e.x[:last], e.y[:last]
+	e.prevX, e.prevY = e.prevX[:last], e.prevY[:last]
+	e.age = e.age[:last]
+}
+
+//update vieillit, intègre et fait rebondir les particules sur obstacles
+func (e *ParticleEmitter) update(s *Space, obstacles []obstacle) {
+	g := s.gravity.Mult(e.gravScale)
+
+	for i := 0; i < len(e.x); {
+		e.age[i]++
+		if e.lifetime > 0 && e.age[i] >= e.lifetime {
+			e.kill(i)
+			continue
+		}
+
+		pos, prev := Vec2{e.x[i], e.y[i]}, Vec2{e.prevX[i], e.prevY[i]}
+		if len(s.attractors) > 0 {
+			g = s.gravityAt(pos, nil).Mult(e.gravScale)
+		}
+
+		// Verlet: la vitesse est implicite dans l'écart avec la position précédente
+		next := pos.Add(pos.Sub(prev)).Add(g)
+		start := pos
+		prev = pos
+		pos = next
+
+		pos, prev = resolvePoint(pos, prev, start, 1, obstacles, e.bounce, e.friction, 0)
+
+		e.x[i], e.y[i] = pos.X, pos.Y
+		e.prevX[i], e.prevY[i] = prev.X, prev.Y
+		i++
+	}
+}
+
+//obstacle forme solide et sa boîte englobante, calculée une fois par tick
+type obstacle struct {
+	shape    Shape
+	min, max Vec2
+}
+
+//obstacles retourne les formes solides de l'espace avec leur boîte englobante
+// Les formes de masse infinie viennent en dernier: un point coincé entre une forme
+// dynamique et le décor finit toujours hors du décor
+func (s *Space) obstacles() []obstacle {
+	list := make([]obstacle, 0, len(s.shapesList))
+	for _, immovable := range []bool{false, true} {
+		for _, shape := range s.shapesList {
+			if shape.IsSolid() && (shape.InvMass() == 0) == immovable {
+				list = append(list, obstacle{shape, shape.Pos(), shape.Pos().Add(Vec2{shape.Width(), shape.Height()})})
+			}
+		}
+	}
+	return list
+}
+
+//pointSkin écart laissé entre un point ramené en surface et la forme, pour qu'il
+// ne glisse pas sur la jointure de deux formes accolées
+const pointSkin float64 = 1e-6
+
+//pointPasses nombre maximum de passes sur les obstacles pour un même point:
+// un point repoussé hors d'une forme peut entrer dans une forme voisine
+const pointPasses int = 4
+
+//resolvePoint ramène un point Verlet hors de tous les obstacles et le fait rebondir.
+// Les passes se répètent tant qu'un obstacle contient le point, au plus pointPasses fois.
+// start est la position du point au début du pas, dt la durée du pas, en ticks.
+// Si mass est positive, les formes dynamiques reçoivent la quantité de mouvement perdue
+// par le point. Retourne les nouvelles positions courante et précédente
+func resolvePoint(pos Vec2, prev Vec2, start Vec2, dt float64, obstacles []obstacle,
+	bounce float64, friction float64, mass float64) (Vec2, Vec2) {
+	for pass := 0; pass < pointPasses; pass++ {
+		hit := false
+		// boîte englobante du déplacement, pour écarter vite les obstacles lointains
+		lo := Vec2{math.Min(start.X, pos.X), math.Min(start.Y, pos.Y)}
+		hi := Vec2{math.Max(start.X, pos.X), math.Max(start.Y, pos.Y)}
+
+		for k, o := range obstacles {
+			if hi.X < o.min.X || lo.X >= o.max.X || hi.Y < o.min.Y || lo.Y >= o.max.Y {
+				continue
+			}
+			blocked := func(q Vec2) bool {
+				for m, other := range obstacles {
+					if m != k && q.X >= other.min.X && q.X < other.max.X && q.Y >= other.min.Y && q.Y < other.max.Y {
+						if _, _, in := pointContact(q, q, other.shape, nil); in {
+							return true
+						}
+					}
+				}
+				return false
+			}
+			before := pos.Sub(prev)
+			var contact bool
+			pos, prev, contact = collidePoint(pos, prev, start, dt, o.shape, blocked, bounce, friction)
+			if !contact {
+				continue
+			}
+			hit = true
+			if mass > 0 && o.shape.InvMass() > 0 {
+				o.shape.ApplyImpulse(before.Sub(pos.Sub(prev)).Mult(mass / dt))
+			}
+			lo = Vec2{math.Min(start.X, pos.X), math.Min(start.Y, pos.Y)}
+			hi = Vec2{math.Max(start.X, pos.X), math.Max(start.Y, pos.Y)}
+		}
+		if !hit {
+			break
+		}
+	}
+	return pos, prev
+}
+
+//pointContact retourne le point juste hors de la surface de shape et la normale
+// sortante, si le point qui va de from à p est entré dans la forme pendant le pas,
+// même s'il l'a traversée. Un point déjà à l'intérieur en from est repoussé par la
+// face la plus proche de from dont la sortie n'est pas bloquée (blocked peut être nil)
+func pointContact(p Vec2, from Vec2, shape Shape, blocked func(Vec2) bool) (Vec2, Vec2, bool) {
+	if circ, ok := shape.(*Circle); ok {
+		return circleContact(p, from, circ.Center(), circ.Radius())
+	}
+
+	min := shape.Pos()
+	max := min.Add(Vec2{shape.Width(), shape.Height()})
+	// intervalles semi-ouverts: un point sur la jointure de deux formes accolées
+	// appartient à l'une d'elles et ne passe pas entre les deux
+	inside := func(q Vec2) bool {
+		return q.X >= min.X && q.X < max.X && q.Y >= min.Y && q.Y < max.Y
+	}
+
+	var n Vec2
+	if inside(from) {
+		if !inside(p) {
+			return p, Vec2{}, false
+		}
+		// déjà à l'intérieur: face libre la plus proche du point de départ
+		n = nearestFreeFace(p, from, min, max, blocked)
+	} else {
+		// intersection du segment avec les deux bandes du rectangle:
+		// la face d'entrée est celle de la bande franchie en dernier
+		d := p.Sub(from)
+		enter, exit := math.Inf(-1), 1.0
+		slabs := [2]struct{ from, d, min, max float64 }{
+			{from.X, d.X, min.X, max.X},
+			{from.Y, d.Y, min.Y, max.Y},
+		}
+		for axis, sl := range slabs {
+			if sl.d == 0 {
+				if sl.from < sl.min || sl.from >= sl.max {
+					return p, Vec2{}, false
+				}
+				continue
+			}
+			t1, t2 := (sl.min-sl.from)/sl.d, (sl.max-sl.from)/sl.d
+			face := -1.0
+			if t1 > t2 {
+				t1, t2, face = t2, t1, 1
+			}
+			if t1 > enter {
+				enter = t1
+				n = Vec2{face, 0}
+				if axis == 1 {
+					n = Vec2{0, face}
+				}
+			}
+			exit = math.Min(exit, t2)
+		}
+		// from est dehors: le segment entre dans la forme si enter est dans [0, exit[
+		if n == (Vec2{}) || enter < 0 || enter >= exit {
+			return p, Vec2{}, false
+		}
+	}
+
+	return faceExit(p, min, max, n), n, true
+}
+
+//faceExit retourne le point juste hors de la face de normale n du rectangle min, max
+// Le point garde son déplacement le long de la face, borné à celle-ci. La borne max
+// est exclue comme dans les intervalles semi-ouverts: le bout de la face n'appartient
+// pas à une forme accolée
+func faceExit(p Vec2, min Vec2, max Vec2, n Vec2) Vec2 {
+	surface := Vec2{Clamp(p.X, min.X, max.X-pointSkin), Clamp(p.Y, min.Y, max.Y-pointSkin)}
+	switch n {
+	case Vec2{-1, 0}:
+		surface.X = min.X - pointSkin
+	case Vec2{1, 0}:
+		surface.X = max.X + pointSkin
+	case Vec2{0, -1}:
+		surface.Y = min.Y - pointSkin
+	default:
+		surface.Y = max.Y + pointSkin
+	}
+	return surface
+}
+
+//nearestFreeFace retourne la normale de la face la plus proche de from, position du
+// point au début du pas, en ignorant les faces dont la sortie de p est dans une autre
+// forme (blocked): des formes qui se recouvrent, comme un mur posé sur le coin d'un sol,
+// se comportent comme une seule
+func nearestFreeFace(p Vec2, from Vec2, min Vec2, max Vec2, blocked func(Vec2) bool) Vec2 {
+	faces := [4]struct {
+		depth float64
+		n     Vec2
+	}{
+		{from.X - min.X, Vec2{-1, 0}},
+		{max.X - from.X, Vec2{1, 0}},
+		{from.Y - min.Y, Vec2{0, -1}},
+		{max.Y - from.Y, Vec2{0, 1}},
+	}
+	sort.Slice(faces[:], func(i, j int) bool { return faces[i].depth < faces[j].depth })
+
+	for _, f := range faces {
+		if blocked == nil || !blocked(faceExit(p, min, max, f.n)) {
+			return f.n
+		}
+	}
+	return faces[0].n
+}
+
+//circleContact pendant de pointContact pour un cercle de centre c et de rayon r
+func circleContact(p Vec2, from Vec2, c Vec2, r float64) (Vec2, Vec2, bool) {
+	hitAt := p
+	if p.Sub(c).Length() >= r {
+		// le point a-t-il traversé le cercle? première racine de |from + t*d - c| = r
+		d := p.Sub(from)
+		f := from.Sub(c)
+		a := d.DotProduct(d)
+		b := f.DotProduct(d)
+		disc := b*b - a*(f.DotProduct(f)-r*r)
+		if a == 0 || disc <= 0 {
+			return p, Vec2{}, false
+		}
+		t := (-b - math.Sqrt(disc)) / a
+		if t < 0 || t > 1 {
+			return p, Vec2{}, false
+		}
+		hitAt = from.Add(d.Mult(t))
+	}
+
+	toPoint := hitAt.Sub(c)
+	n := Vec2{0, -1}
+	if dist := toPoint.Length(); dist > 0 {
+		n = toPoint.Div(dist)
+	}
+	return c.Add(n.Mult(r + pointSkin)), n, true
+}
+
+//collidePoint ramène un point Verlet à la surface de shape et fait rebondir
+// sa vitesse relative à la forme. start est la position du point au début du pas,
+// dt la durée du pas, en ticks. blocked est passée à pointContact.
+// Retourne les nouvelles positions courante et précédente, et true s'il y a eu contact
+func collidePoint(pos Vec2, prev Vec2, start Vec2, dt float64, shape Shape, blocked func(Vec2) bool,
+	bounce float64, friction float64) (Vec2, Vec2, bool) {
+	surface, n, hit := pointContact(pos, start, shape, blocked)
+	if !hit {
+		return pos, prev, false
+	}
+
+	shapeStep := shape.Velocity().Mult(dt)
+	v := pos.Sub(prev).Sub(shapeStep)
+	if vn := v.DotProduct(n); vn < 0 {
+		vt := v.Sub(n.Mult(vn))
+		v = vt.Mult(1 - friction).Sub(n.Mult(vn * bounce))
+	}
+	v = v.Add(shapeStep)
+
+	return surface, surface.Sub(v), true
+}
+
+//ParticleEmitters retourne la liste des émetteurs de particules de l'espace
+func (s *Space) ParticleEmitters() []*ParticleEmitter {
+	return s.emitters
+}
+
+//AddParticleEmitter ajoute un émetteur de particules à l'espace
+func (s *Space) AddParticleEmitter(e *ParticleEmitter) {
+	s.emitters = append(s.emitters, e)
+}
+
+//RemoveParticleEmitter supprime un émetteur de particules de l'espace
+func (s *Space) RemoveParticleEmitter(e *ParticleEmitter) {
+	for i, emitter := range s.emitters {
+		if emitter == e {
+			copy(s.emitters[i:], s.emitters[i+1:])
+			s.emitters[len(s.emitters)-1] = nil
+			s.emitters = s.emitters[:len(s.emitters)-1]
+			return
+		}
+	}
+}
+
+//updateParticles met à jour les particules de tous les émetteurs, les corps mous
+// et les fluides SPH
+func (s *Space) updateParticles() {
+	if len(s.emitters) == 0 && len(s.softBodies) == 0 && len(s.sphFluids) == 0 {
+		return
+	}
+	obstacles := s.obstacles()
+	for _, e := range s.emitters {
+		e.update(s, obstacles)
+	}
+	for _, b := range s.softBodies {
+		b.update(s, obstacles)
+	}
+	for _, f := range s.sphFluids {
+		f.update(s, obstacles)
+	}
+}
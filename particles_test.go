@@ -0,0 +1,47 @@
+package physics
+
+import (
+	"math/rand"
+	"testing"
+)
+
+//cornerSpace retourne un espace avec un sol et un mur statiques accolés en coin
+func cornerSpace() (*Space, *Rectangle, *Rectangle) {
+	s := &Space{}
+	s.SetGravity(Vec2{0, 0.5})
+	floor := NewRectangle(Vec2{0, 200}, 200, 20)
+	wall := NewRectangle(Vec2{200, 0}, 20, 220)
+	for _, r := range []*Rectangle{floor, wall} {
+		r.SetStatic(true)
+		s.AddShape(r)
+	}
+	return s, floor, wall
+}
+
+//escaped retourne true si p est passé dans le mur ou sous le sol
+func escaped(p Vec2, floor *Rectangle, wall *Rectangle) bool {
+	underFloor := p.X >= floor.Pos().X && p.X < wall.Pos().X && p.Y >= floor.Pos().Y
+	return underFloor || p.X >= wall.Pos().X
+}
+
+func TestParticlesStayInCorner(t *testing.T) {
+	s, floor, wall := cornerSpace()
+	e := NewParticleEmitter()
+	e.SetBounce(0.5)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		pos := Vec2{150 + rng.Float64()*50, 150 + rng.Float64()*50}
+		vel := Vec2{rng.Float64()*6 - 3, rng.Float64()*6 - 3}
+		e.Emit(pos, vel)
+	}
+	s.AddParticleEmitter(e)
+
+	for tick := 0; tick < 200; tick++ {
+		s.Update()
+		for i := 0; i < e.Count(); i++ {
+			if p := e.Position(i); escaped(p, floor, wall) {
+				t.Fatalf("tick %d: particle %d escaped the corner at %v", tick, i, p)
+			}
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package physics
+
+import "math"
+
+//Actor rectangle déplacé au pixel près, à la manière de Celeste
+// Les positions restent entières: les fractions de pixel s'accumulent dans un reste.
+// Un Actor avance d'un pixel à la fois et s'arrête exactement au contact
+// des Solid et des rectangles statiques solides de l'espace.
+// Les Actor ne sont pas mis à jour par Space.Update: le jeu appelle MoveX et MoveY
+type Actor struct {
+	*Rectangle
+	space     *Space
+	remainder Vec2
+	onSquish  func(a *Actor, by Shape)
+}
+
+//Solid rectangle déplacé au pixel près qui pousse et porte les Actor
+type Solid struct {
+	*Rectangle
+	space      *Space
+	remainder  Vec2
+	collidable bool
+}
+
+//AddActor ajoute rect à l'espace comme Actor, à la position arrondie au pixel
+func (s *Space) AddActor(rect *Rectangle) *Actor {
+	rect.SetPos(roundVec(rect.Pos()))
+	a := &Actor{Rectangle: rect, space: s}
+	s.actors = append(s.actors, a)
+	return a
+}
+
+//AddSolid ajoute rect à l'espace comme Solid, à la position arrondie au pixel
+func (s *Space) AddSolid(rect *Rectangle) *Solid {
+	rect.SetPos(roundVec(rect.Pos()))
+	solid := &Solid{Rectangle: rect, space: s, collidable: true}
+	s.solids = append(s.solids, solid)
+	return solid
+}
+
+//Actors retourne la liste des Actor de l'espace
+func (s *Space) Actors() []*Actor {
+	return s.actors
+}
+
+//Solids retourne la liste des Solid de l'espace
+func (s *Space) Solids() []*Solid {
+	return s.solids
+}
+
+//RemoveActor supprime un Actor de l'espace
+func (s *Space) RemoveActor(a *Actor) {
+	for i, actor := range s.actors {
+		if actor == a {
+			copy(s.actors[i:], s.actors[i+1:])
+			s.actors[len(s.actors)-1] = nil
+			s.actors = s.actors[:len(s.actors)-1]
+			return
+		}
+	}
+}
+
+//RemoveSolid supprime un Solid de l'espace
+func (s *Space) RemoveSolid(solid *Solid) {
+	for i, sl := range s.solids {
+		if sl == solid {
+			copy(s.solids[i:], s.solids[i+1:])
+			s.solids[len(s.solids)-1] = nil
+			s.solids = s.solids[:len(s.solids)-1]
+			return
+		}
+	}
+}
+
+//SetSquishHandler mets la fonction appelée quand un Solid écrase l'Actor
+// contre un obstacle. by est la forme contre laquelle l'Actor est bloqué
+func (a *Actor) SetSquishHandler(f func(a *Actor, by Shape)) {
+	a.onSquish = f
+}
+
+//Remainder retourne les fractions de pixel accumulées
+func (a *Actor) Remainder() Vec2 {
+	return a.remainder
+}
+
+//MoveX déplace l'Actor horizontalement de amount pixels, fractions accumulées
+// Si un obstacle bloque, le déplacement s'arrête au contact, onCollide est appelée
+// avec l'obstacle (si non nil) et MoveX retourne true
+func (a *Actor) MoveX(amount float64, onCollide func(Shape)) bool {
+	a.remainder.X += amount
+	move := math.Round(a.remainder.X)
+	a.remainder.X -= move
+	return a.moveExact(Vec2{move, 0}, onCollide)
+}
+
+//MoveY déplace l'Actor verticalement de amount pixels, voir MoveX
+func (a *Actor) MoveY(amount float64, onCollide func(Shape)) bool {
+	a.remainder.Y += amount
+	move := math.Round(a.remainder.Y)
+	a.remainder.Y -= move
+	return a.moveExact(Vec2{0, move}, onCollide)
+}
+
+//moveExact avance pixel par pixel sur un axe jusqu'à move ou jusqu'à un obstacle
+func (a *Actor) moveExact(move Vec2, onCollide func(Shape)) bool {
+	step := Vec2{Sign(move.X), 0}
+	count := Abs(move.X)
+	if move.X == 0 {
+		step = Vec2{0, Sign(move.Y)}
+		count = Abs(move.Y)
+	}
+
+	for ; count > 0; count-- {
+		next := a.Pos().Add(step)
+		if blocker := a.collideAt(next); blocker != nil {
+			if onCollide != nil {
+				onCollide(blocker)
+			}
+			return true
+		}
+		a.SetPos(next)
+	}
+	return false
+}
+
+//collideAt retourne l'obstacle que l'Actor chevaucherait en pos, nil si aucun
+func (a *Actor) collideAt(pos Vec2) Shape {
+	min, max := pos, pos.Add(Vec2{a.Width(), a.Height()})
+
+	for _, solid := range a.space.solids {
+		if solid.collidable && overlapsStrict(min, max, solid.Pos(), solid.getMax()) {
+			return solid
+		}
+	}
+	for _, shape := range a.space.shapesList {
+		rect, ok := shape.(*Rectangle)
+		if ok && rect.IsStatic() && rect.IsSolid() && overlapsStrict(min, max, rect.Pos(), rect.getMax()) {
+			return rect
+		}
+	}
+	return nil
+}
+
+//IsRiding retourne true si l'Actor est posé sur solid
+func (a *Actor) IsRiding(solid *Solid) bool {
+	pos := a.Pos().Add(Vec2{0, 1})
+	return overlapsStrict(pos, pos.Add(Vec2{a.Width(), a.Height()}), solid.Pos(), solid.getMax())
+}
+
+//squish signale à l'Actor qu'il est écrasé par solid contre by
+func (a *Actor) squish(solid *Solid, by Shape) {
+	if a.onSquish != nil {
+		a.onSquish(a, by)
+	}
+	a.space.emitCrush(&CrushEvent{shape: a, crushers: []Shape{solid, by}})
+}
+
+//Remainder retourne les fractions de pixel accumulées
+func (s *Solid) Remainder() Vec2 {
+	return s.remainder
+}
+
+//IsCollidable retourne true si le Solid bloque les Actor
+func (s *Solid) IsCollidable() bool {
+	return s.collidable
+}
+
+//SetCollidable rend le Solid bloquant ou non pour les Actor
+func (s *Solid) SetCollidable(b bool) {
+	s.collidable = b
+}
+
+//Move déplace le Solid de x, y pixels, fractions accumulées
+// Les Actor sur son chemin sont poussés, ceux qui sont posés dessus sont portés.
+// Un Actor poussé contre un obstacle est écrasé (voir Actor.SetSquishHandler)
+func (s *Solid) Move(x float64, y float64) {
+	s.remainder = s.remainder.Add(Vec2{x, y})
+	moveX := math.Round(s.remainder.X)
+	moveY := math.Round(s.remainder.Y)
+	if moveX == 0 && moveY == 0 {
+		return
+	}
+
+	riding := []*Actor{}
+	for _, a := range s.space.actors {
+		if a.IsRiding(s) {
+			riding = append(riding, a)
+		}
+	}
+
+	// le Solid ne bloque pas les Actor qu'il déplace
+	s.collidable = false
+
+	if moveX != 0 {
+		s.remainder.X -= moveX
+		s.SetPos(s.Pos().Add(Vec2{moveX, 0}))
+		s.carry(riding, Vec2{moveX, 0})
+	}
+	if moveY != 0 {
+		s.remainder.Y -= moveY
+		s.SetPos(s.Pos().Add(Vec2{0, moveY}))
+		s.carry(riding, Vec2{0, moveY})
+	}
+
+	s.collidable = true
+}
+
+//carry pousse les Actor que le Solid chevauche après un déplacement de move sur un axe,
+// et porte ceux de riding
+func (s *Solid) carry(riding []*Actor, move Vec2) {
+	for _, a := range s.space.actors {
+		if overlapsStrict(a.Pos(), a.getMax(), s.Pos(), s.getMax()) {
+			// pousse l'Actor jusqu'au bord du Solid
+			push := Vec2{}
+			switch {
+			case move.X > 0:
+				push.X = s.getMax().X - a.Pos().X
+			case move.X < 0:
+				push.X = s.Pos().X - a.getMax().X
+			case move.Y > 0:
+				push.Y = s.getMax().Y - a.Pos().Y
+			default:
+				push.Y = s.Pos().Y - a.getMax().Y
+			}
+
+			if !a.moveExact(push, func(by Shape) { a.squish(s, by) }) {
+				continue
+			}
+		} else if actorIn(riding, a) {
+			a.moveExact(move, nil)
+		}
+	}
+}
+
+//actorIn retourne true si a est dans la liste
+func actorIn(actors []*Actor, a *Actor) bool {
+	for _, actor := range actors {
+		if actor == a {
+			return true
+		}
+	}
+	return false
+}
+
+//overlapsStrict retourne true si les rectangles se chevauchent, bords exclus
+func overlapsStrict(min1 Vec2, max1 Vec2, min2 Vec2, max2 Vec2) bool {
+	return max1.X > min2.X && min1.X < max2.X && max1.Y > min2.Y && min1.Y < max2.Y
+}
+
+//roundVec arrondit les deux composantes au pixel
+func roundVec(v Vec2) Vec2 {
+	return Vec2{math.Round(v.X), math.Round(v.Y)}
+}
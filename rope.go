@@ -0,0 +1,141 @@
+package physics
+
+//Rope corde formée de segments circulaires reliés par des contraintes de distance maximale
+// Les segments sont des formes de l'espace: ils collisionnent avec le reste du monde
+type Rope struct {
+	space        *Space
+	segments     []*Circle
+	links        []*DistanceJoint
+	startJoint   *DistanceJoint
+	endJoint     *DistanceJoint
+	segLength    float64
+	breakTension float64
+}
+
+//NewRope crée une corde de count segments entre start et end et l'ajoute à l'espace
+// Chaque segment est un cercle de rayon radius et de masse mass. Le rayon doit rester
+// inférieur à la moitié de la longueur d'un segment pour que les voisins ne se touchent pas
+func NewRope(space *Space, start Vec2, end Vec2, count int, radius float64, mass float64) *Rope {
+	if count < 1 {
+		count = 1
+	}
+
+	r := &Rope{space: space}
+	step := end.Sub(start).Div(float64(count))
+	r.segLength = step.Length()
+
+	for i := 0; i < count; i++ {
+		seg := NewCircle(start.Add(step.Mult(float64(i)+0.5)), radius)
+		seg.SetMass(mass)
+		seg.SetTags([]string{"rope"})
+		r.segments = append(r.segments, seg)
+		space.AddShape(seg)
+
+		if i > 0 {
+			link := NewDistanceJoint(r.segments[i-1], seg, Vec2{}, Vec2{})
+			link.SetLimits(0, r.segLength)
+			r.links = append(r.links, link)
+			space.AddJoint(link)
+		}
+	}
+	return r
+}
+
+//Segments retourne les segments de la corde
+func (r *Rope) Segments() []*Circle {
+	return r.segments
+}
+
+//Links retourne les contraintes entre segments, dans l'ordre
+// Les contraintes d'extrémité n'en font pas partie
+func (r *Rope) Links() []*DistanceJoint {
+	return r.links
+}
+
+//AttachStart attache le premier segment à shape
+// Si shape est nil, anchor est un point du monde
+func (r *Rope) AttachStart(shape Shape, anchor Vec2) {
+	r.startJoint = r.attach(r.startJoint, r.segments[0], shape, anchor)
+}
+
+//AttachEnd attache le dernier segment à shape
+// Si shape est nil, anchor est un point du monde
+func (r *Rope) AttachEnd(shape Shape, anchor Vec2) {
+	r.endJoint = r.attach(r.endJoint, r.segments[len(r.segments)-1], shape, anchor)
+}
+
+//DetachStart libère le premier segment
+func (r *Rope) DetachStart() {
+	r.startJoint = r.attach(r.startJoint, nil, nil, Vec2{})
+}
+
+//DetachEnd libère le dernier segment
+func (r *Rope) DetachEnd() {
+	r.endJoint = r.attach(r.endJoint, nil, nil, Vec2{})
+}
+
+//attach remplace la contrainte d'extrémité old par une contrainte entre seg et shape
+// Si seg est nil, la contrainte est seulement supprimée
+func (r *Rope) attach(old *DistanceJoint, seg *Circle, shape Shape, anchor Vec2) *DistanceJoint {
+	if old != nil {
+		r.space.RemoveJoint(old)
+	}
+	if seg == nil {
+		return nil
+	}
+
+	j := NewDistanceJoint(seg, shape, Vec2{}, anchor)
+	j.SetLimits(0, r.segLength/2)
+	j.SetBreakTension(r.breakTension)
+	r.space.AddJoint(j)
+	return j
+}
+
+//joints retourne toutes les contraintes de la corde, extrémités comprises
+func (r *Rope) joints() []*DistanceJoint {
+	joints := append([]*DistanceJoint{}, r.links...)
+	if r.startJoint != nil {
+		joints = append(joints, r.startJoint)
+	}
+	if r.endJoint != nil {
+		joints = append(joints, r.endJoint)
+	}
+	return joints
+}
+
+//Tension retourne la tension maximale le long de la corde au dernier pas
+func (r *Rope) Tension() float64 {
+	tension := 0.0
+	for _, j := range r.joints() {
+		tension = Max(tension, j.Tension())
+	}
+	return tension
+}
+
+//Tensions retourne la tension de chaque lien entre segments
+func (r *Rope) Tensions() []float64 {
+	tensions := make([]float64, len(r.links))
+	for i, j := range r.links {
+		tensions[i] = j.Tension()
+	}
+	return tensions
+}
+
+//SetBreakTension rend la corde cassable au-delà de la tension t, 0 pour incassable
+// Les liens rompus sont retirés de l'espace par Space.Update
+func (r *Rope) SetBreakTension(t float64) {
+	r.breakTension = t
+	for _, j := range r.joints() {
+		j.SetBreakTension(t)
+	}
+}
+
+//IsBroken retourne true si un des liens de la corde a rompu
+func (r *Rope) IsBroken() bool {
+	for _, j := range r.joints() {
+		if j.Broken() {
+			return true
+		}
+	}
+	return false
+}
@@ -1,5 +1,12 @@
 package physics
 
+import "math"
+
+const (
+	// densité des nouvelles formes: elles ont une masse dès leur création
+	defaultDensity float64 = 1
+)
+
 //Shape type de la forme
 type Shape interface {
 	Pos() Vec2
@@ -15,19 +22,55 @@ type Shape interface {
 	SetMaxVel(Vec2)
 	Accel() Vec2
 	SetAccel(Vec2)
+	Force() Vec2
+	ApplyForce(Vec2)
+	ApplyForceAtPoint(Vec2, Vec2)
+	ApplyImpulse(Vec2)
 	MaxAccel() Vec2
 	SetMaxAccel(Vec2)
 	Gravity() Vec2
 	SetGravity(Vec2)
+	ResetGravity()
+	UsesSpaceGravity() bool
+	GravityScale() float64
+	SetGravityScale(float64)
+	Drag() (float64, float64)
+	SetDrag(float64, float64)
+	ResetDrag()
+	UsesSpaceDrag() bool
 	IsGrounded() bool
 	SetGrounded(bool)
+	Ground() Shape
+	SetGround(Shape)
+	GroundNormal() Vec2
+	SetGroundNormal(Vec2)
+	SlopeAngle() float64
+	OnWallLeft() bool
+	OnWallRight() bool
+	OnCeiling() bool
+	Touching(ContactSide) []Shape
+	AddTouching(ContactSide, Shape)
 	IsStatic() bool
 	SetStatic(bool)
+	IsKinematic() bool
+	SetKinematic(bool)
 	IsSolid() bool
 	SetSolid(bool)
+	IsSleeping() bool
+	Sleep()
+	Wake()
 	Friction() float64
 	SetFriction(float64)
+	Material() *Material
+	SetMaterial(*Material)
+	Area() float64
+	Mass() float64
+	SetMass(float64)
 	InvMass() float64
+	Density() float64
+	SetDensity(float64)
+	CenterOfMass() Vec2
+	Inertia() float64
 	Elasticity() float64
 	SetElasticity(float64)
 	ShapeName() string
@@ -42,16 +85,32 @@ type BasicShape struct {
 	pos        Vec2
 	velocity   Vec2
 	accel      Vec2
+	force      Vec2 //somme des forces appliquées depuis la dernière intégration
 	gravity    Vec2
+	ownGravity bool    //false si la forme suit la gravité de l'espace
+	gravScale  float64 //multiplie la gravité de l'espace
+	linearDrag float64
+	quadDrag   float64
+	ownDrag    bool //false si la forme utilise la résistance de l'espace
 	maxVel     Vec2 //vitesse maximale
 	maxAccel   Vec2 //accélération maximale
 	grounded   bool
+	ground     Shape //forme sur laquelle repose la forme
+	groundNorm Vec2  //normale du sol, de la forme support vers la forme
+	touching   [contactSides][]Shape
 	static     bool
+	kinematic  bool
 	solid      bool
+	sleeping   bool
+	idleTime   float64 //nombre de ticks passés sous la vitesse de sommeil
+	idlePos    Vec2    //position lors du dernier décompte d'immobilité
+	idleVel    Vec2    //moyenne glissante du déplacement par tick
+	density    float64
 	mass       float64
 	invMass    float64
 	elasticity float64
 	friction   float64
+	material   *Material
 	name       string
 	tags       []string
 }
@@ -64,19 +123,26 @@ func (s *BasicShape) Pos() Vec2 {
 //UpdatePos met à jour position avec la vitesse
 // La fonction SetPos est définie sur les shape parce que
 // Circle doit mettre à jour le centre
+// Une forme cinématique n'est déplacée que par sa vitesse
 func (s *BasicShape) UpdatePos() {
-	// clamp accel
-	s.clampAccel()
+	if !s.kinematic {
+		// clamp accel
+		s.clampAccel()
 
-	//ajout accélération et gravité
-	s.velocity = s.velocity.Add(s.accel).Add(s.gravity)
+		//ajout accélération, gravité et forces accumulées
+		s.velocity = s.velocity.Add(s.accel).Add(s.gravity).Add(s.force.Mult(s.InvMass()))
 
-	s.clampVelocity()
+		s.clampVelocity()
+	}
 
 	s.Kind.SetPos(s.Pos().Add(s.Velocity()))
 
+	//les forces ne valent que pour un pas
+	s.force = Vec2{}
+
 	//reset ground state
 	s.SetGrounded(false)
+	s.clearTouching()
 
 }
 
@@ -94,9 +160,10 @@ func (s *BasicShape) Velocity() Vec2 {
 	return s.velocity
 }
 
-//SetVelocity mets la vitesse à v
+//SetVelocity mets la vitesse à v et réveille la forme
 func (s *BasicShape) SetVelocity(v Vec2) {
 	s.velocity = v
+	s.Wake()
 }
 
 //MaxVel retourne la vitesse maximum de la shape
@@ -109,11 +176,33 @@ func (s *BasicShape) SetMaxVel(v Vec2) {
 	s.maxVel = v
 }
 
-// //AddForces ajoute un vecteur (Vec2.Add()) au vecteur existant (initialisé à 0.0)
-// // le vecteur est remis à 0 à chaque thick
-// func (s *BasicShape) AddForce(v Vec2) {
-// 	s.force.Add(v)
-// }
+//Force retourne la somme des forces appliquées depuis la dernière intégration
+func (s *BasicShape) Force() Vec2 {
+	return s.force
+}
+
+//ApplyForce ajoute f aux forces appliquées à la forme et la réveille
+// Les forces s'accumulent pendant le pas, sont divisées par la masse
+// à l'intégration puis remises à zéro
+func (s *BasicShape) ApplyForce(f Vec2) {
+	if f == (Vec2{}) {
+		return
+	}
+	s.force = s.force.Add(f)
+	s.Wake()
+}
+
+//ApplyForceAtPoint applique la force f au point p (coordonnées monde)
+// Les formes ne tournent pas: seul l'effet linéaire de la force est appliqué
+func (s *BasicShape) ApplyForceAtPoint(f Vec2, p Vec2) {
+	s.ApplyForce(f)
+}
+
+//ApplyImpulse change immédiatement la vitesse de j divisé par la masse et réveille la forme
+func (s *BasicShape) ApplyImpulse(j Vec2) {
+	s.velocity = s.velocity.Add(j.Mult(s.InvMass()))
+	s.Wake()
+}
 
 //clampAccel restreint l'accélération à -maxAccel, maxAccel
 // Si maxAccel est à 0,0 l'accélération n'est pas restreinte
@@ -149,22 +238,164 @@ func (s *BasicShape) Gravity() Vec2 {
 	return s.gravity
 }
 
+//Drag retourne les coefficients de résistance linéaire et quadratique propres à la forme
+func (s *BasicShape) Drag() (float64, float64) {
+	return s.linearDrag, s.quadDrag
+}
+
+//SetDrag mets les coefficients de résistance propres à la forme, qui remplacent ceux de l'espace
+// linear freine proportionnellement à la vitesse, quadratic au carré de la vitesse
+func (s *BasicShape) SetDrag(linear float64, quadratic float64) {
+	s.linearDrag = linear
+	s.quadDrag = quadratic
+	s.ownDrag = true
+}
+
+//ResetDrag fait de nouveau utiliser à la forme la résistance de l'espace
+func (s *BasicShape) ResetDrag() {
+	s.linearDrag = 0
+	s.quadDrag = 0
+	s.ownDrag = false
+}
+
+//UsesSpaceDrag retourne true si la forme utilise la résistance de l'espace
+func (s *BasicShape) UsesSpaceDrag() bool {
+	return !s.ownDrag
+}
+
+//ContactSide côté d'une forme touché par une autre, relatif à sa gravité
+type ContactSide int
+
+const (
+	//SideWallLeft mur à gauche de la forme
+	SideWallLeft ContactSide = iota
+	//SideWallRight mur à droite de la forme
+	SideWallRight
+	//SideCeiling plafond au-dessus de la forme
+	SideCeiling
+
+	contactSides = iota
+)
+
+//OnWallLeft retourne true si la forme touche un mur à sa gauche
+func (s *BasicShape) OnWallLeft() bool {
+	return len(s.touching[SideWallLeft]) > 0
+}
+
+//OnWallRight retourne true si la forme touche un mur à sa droite
+func (s *BasicShape) OnWallRight() bool {
+	return len(s.touching[SideWallRight]) > 0
+}
+
+//OnCeiling retourne true si la forme touche un plafond
+func (s *BasicShape) OnCeiling() bool {
+	return len(s.touching[SideCeiling]) > 0
+}
+
+//Touching retourne une copie des formes qui touchent ce côté de la forme au dernier pas
+func (s *BasicShape) Touching(side ContactSide) []Shape {
+	return append([]Shape(nil), s.touching[side]...)
+}
+
+//AddTouching ajoute other aux formes qui touchent ce côté
+func (s *BasicShape) AddTouching(side ContactSide, other Shape) {
+	s.touching[side] = append(s.touching[side], other)
+}
+
+//clearTouching oublie les contacts latéraux du pas précédent
+func (s *BasicShape) clearTouching() {
+	for i := range s.touching {
+		s.touching[i] = s.touching[i][:0]
+	}
+}
+
 //IsGrounded retourne true si la forme est au sol
 func (s *BasicShape) IsGrounded() bool {
 	return s.grounded
 }
 
 //SetGrounded mets le statut au sol à true ou false
+// Une forme qui n'est plus au sol n'a plus de support
 func (s *BasicShape) SetGrounded(b bool) {
 	s.grounded = b
+	if !b {
+		s.ground = nil
+		s.groundNorm = Vec2{}
+	}
+}
+
+//Ground retourne la forme sur laquelle repose la forme, nil si pas au sol
+func (s *BasicShape) Ground() Shape {
+	return s.ground
 }
 
-//SetGravity mets la gravité à 'g'
+//SetGround mets la forme support à g
+func (s *BasicShape) SetGround(g Shape) {
+	s.ground = g
+}
+
+//GroundNormal retourne la normale du sol, orientée vers la forme, nulle si pas au sol
+func (s *BasicShape) GroundNormal() Vec2 {
+	return s.groundNorm
+}
+
+//SetGroundNormal mets la normale du sol à n
+func (s *BasicShape) SetGroundNormal(n Vec2) {
+	s.groundNorm = n
+}
+
+//SlopeAngle retourne l'angle en radians entre le sol et le "haut" local
+// de la forme, opposé à sa gravité. 0 sur un sol plat ou pas au sol
+func (s *BasicShape) SlopeAngle() float64 {
+	if !s.grounded || s.groundNorm == (Vec2{}) {
+		return 0
+	}
+	return math.Acos(Clamp(s.groundNorm.DotProduct(upDirection(s.Kind)), -1, 1))
+}
+
+//SetGravity mets la gravité propre à la forme à 'g', qui remplace celle de l'espace
+// et des attracteurs
 func (s *BasicShape) SetGravity(g Vec2) {
 	s.gravity = g
+	s.ownGravity = true
+}
+
+//ResetGravity fait de nouveau suivre à la forme la gravité de l'espace
+func (s *BasicShape) ResetGravity() {
+	s.gravity = Vec2{}
+	s.ownGravity = false
+}
+
+//UsesSpaceGravity retourne true si la forme suit la gravité de l'espace
+func (s *BasicShape) UsesSpaceGravity() bool {
+	return !s.ownGravity
+}
+
+//setSpaceGravity mets la gravité reçue de l'espace, sans effet si la forme a sa propre gravité
+func (s *BasicShape) setSpaceGravity(g Vec2) {
+	if !s.ownGravity {
+		s.gravity = g
+	}
+}
+
+//GravityScale retourne le facteur appliqué à la gravité de l'espace
+func (s *BasicShape) GravityScale() float64 {
+	return s.gravScale
+}
+
+//SetGravityScale mets le facteur appliqué à la gravité de l'espace à f
+// 1 par défaut, 0 pour ignorer la gravité, négatif pour l'inverser
+func (s *BasicShape) SetGravityScale(f float64) {
+	s.gravScale = f
+}
+
+//Mass retourne la masse
+func (s *BasicShape) Mass() float64 {
+	return s.mass
 }
 
 //SetMass mets la masse à m
+// La densité est ajustée pour rester cohérente avec l'aire de la forme
 func (s *BasicShape) SetMass(mass float64) {
 	s.mass = mass
 
@@ -173,10 +404,34 @@ func (s *BasicShape) SetMass(mass float64) {
 	} else {
 		s.invMass = 1 / mass
 	}
+
+	if area := s.Kind.Area(); area > 0 {
+		s.density = mass / area
+	}
+}
+
+//Density retourne la densité (masse par unité d'aire)
+func (s *BasicShape) Density() float64 {
+	return s.density
+}
+
+//SetDensity mets la densité à d et recalcule la masse à partir de l'aire
+func (s *BasicShape) SetDensity(d float64) {
+	s.SetMass(d * s.Kind.Area())
+	s.density = d
+}
+
+//CenterOfMass retourne le centre de masse: le centre, les formes étant homogènes
+func (s *BasicShape) CenterOfMass() Vec2 {
+	return s.Kind.Center()
 }
 
 //InvMass retourne la masse inverse
+// Les formes statiques et cinématiques ont une masse infinie: leur masse inverse est 0
 func (s *BasicShape) InvMass() float64 {
+	if s.static || s.kinematic {
+		return 0
+	}
 	return s.invMass
 }
 
@@ -200,6 +455,22 @@ func (s *BasicShape) SetFriction(f float64) {
 	s.friction = f
 }
 
+//Material retourne le matériau de la forme, nil si aucun
+func (s *BasicShape) Material() *Material {
+	return s.material
+}
+
+//SetMaterial attribue le matériau m à la forme: son élasticité et sa friction
+// prennent les valeurs du matériau. Les contacts lisent les valeurs du matériau,
+// une modification du matériau vaut donc pour toutes ses formes
+func (s *BasicShape) SetMaterial(m *Material) {
+	s.material = m
+	if m != nil {
+		s.elasticity = m.Restitution
+		s.friction = m.Friction
+	}
+}
+
 //IsStatic retourne true si la forme est de type statique
 func (s *BasicShape) IsStatic() bool {
 	return s.static
@@ -210,6 +481,23 @@ func (s *BasicShape) SetStatic(b bool) {
 	s.static = b
 }
 
+//IsKinematic retourne true si la forme est cinématique
+// Une forme cinématique est déplacée par sa vitesse, ignore gravité et accélération,
+// et a une masse infinie lors de la résolution des collisions: elle pousse
+// les formes dynamiques sans jamais être repoussée
+func (s *BasicShape) IsKinematic() bool {
+	return s.kinematic
+}
+
+//SetKinematic mets la forme à cinématique ou non
+// Une forme cinématique ne dort jamais: elle est réveillée si elle dormait
+func (s *BasicShape) SetKinematic(b bool) {
+	s.kinematic = b
+	if b {
+		s.Wake()
+	}
+}
+
 //IsSolid retourne true si la forme est de type solide, false sinon
 func (s *BasicShape) IsSolid() bool {
 	return s.solid
@@ -220,6 +508,51 @@ func (s *BasicShape) SetSolid(b bool) {
 	s.solid = b
 }
 
+//IsSleeping retourne true si la forme est endormie
+// Une forme endormie n'est ni déplacée ni testée contre les formes immobiles
+func (s *BasicShape) IsSleeping() bool {
+	return s.sleeping
+}
+
+//Sleep endort la forme et annule sa vitesse
+// Sans effet sur une forme cinématique, qui garde la vitesse qu'on lui impose
+func (s *BasicShape) Sleep() {
+	if s.kinematic {
+		return
+	}
+	s.sleeping = true
+	s.velocity = Vec2{}
+}
+
+//Wake réveille la forme et remet son compteur d'immobilité à zéro
+// Sans effet sur une forme éveillée
+func (s *BasicShape) Wake() {
+	if s.sleeping {
+		s.sleeping = false
+		s.idleTime = 0
+	}
+}
+
+//tickIdle compte les ticks passés sous la vitesse maxVel et retourne leur nombre
+// La vitesse mesurée est la moyenne glissante du déplacement réel: une forme posée sur
+// une pile garde une vitesse résiduelle et tremble après résolution, sans se déplacer.
+// Une forme endormie garde son compteur
+func (s *BasicShape) tickIdle(maxVel float64) float64 {
+	if s.sleeping {
+		return s.idleTime
+	}
+
+	s.idleVel = s.idleVel.Mult(0.9).Add(s.pos.Sub(s.idlePos).Mult(0.1))
+	s.idlePos = s.pos
+
+	if s.idleVel.Length() < maxVel {
+		s.idleTime++
+	} else {
+		s.idleTime = 0
+	}
+	return s.idleTime
+}
+
 //SetTags attribue la liste des tags
 func (s *BasicShape) SetTags(tags []string) {
 	s.tags = tags
@@ -257,6 +590,16 @@ func (r *Rectangle) Height() float64 {
 	return r.height
 }
 
+//Area retourne l'aire du rectangle
+func (r *Rectangle) Area() float64 {
+	return r.width * r.height
+}
+
+//Inertia retourne le moment d'inertie autour du centre de masse
+func (r *Rectangle) Inertia() float64 {
+	return r.mass * (r.width*r.width + r.height*r.height) / 12
+}
+
 //getMax retourne le max
 func (r *Rectangle) getMax() Vec2 {
 	return Vec2{r.Pos().X + r.Width(), r.Pos().Y + r.Height()}
@@ -296,9 +639,10 @@ func (r *Rectangle) Name() string {
 //NewRectangle Crée un rectangle
 func NewRectangle(pos Vec2, width float64, height float64) *Rectangle {
 	rect := &Rectangle{width: width, height: height}
-	rect.BasicShape = &BasicShape{Kind: rect, pos: pos}
+	rect.BasicShape = &BasicShape{Kind: rect, pos: pos, gravScale: 1}
 	rect.SetName(UUID())
 	rect.SetSolid(true)
+	rect.SetDensity(defaultDensity)
 	return rect
 }
 
@@ -351,6 +695,16 @@ func (s *Circle) Radius() float64 {
 	return s.radius
 }
 
+//Area retourne l'aire du cercle
+func (s *Circle) Area() float64 {
+	return math.Pi * s.radius * s.radius
+}
+
+//Inertia retourne le moment d'inertie autour du centre de masse
+func (s *Circle) Inertia() float64 {
+	return s.mass * s.radius * s.radius / 2
+}
+
 //Width retourne la largeur
 func (s *Circle) Width() float64 {
 	return s.radius * 2
@@ -364,8 +718,10 @@ func (s *Circle) Height() float64 {
 //NewCircle créé un nouveau cercle
 func NewCircle(center Vec2, radius float64) *Circle {
 	circ := &Circle{radius: radius}
-	circ.BasicShape = &BasicShape{Kind: circ, pos: center.SubScalar(radius)}
+	circ.BasicShape = &BasicShape{Kind: circ, gravScale: 1}
+	circ.SetCenter(center)
 	circ.SetName(UUID())
 	circ.SetSolid(true)
+	circ.SetDensity(defaultDensity)
 	return circ
 }
@@ -0,0 +1,248 @@
+package physics
+
+//sleeper est implémentée par les formes qui comptent leur temps d'immobilité
+type sleeper interface {
+	tickIdle(maxVel float64) float64
+}
+
+//SetSleepEnabled active ou désactive la mise en sommeil des formes immobiles
+func (s *Space) SetSleepEnabled(b bool) {
+	s.sleepEnabled = b
+
+	if !b {
+		for _, shape := range s.shapesList {
+			shape.Wake()
+		}
+		s.sleepIslands = nil
+	}
+}
+
+//IsSleepEnabled retourne true si la mise en sommeil est active
+func (s *Space) IsSleepEnabled() bool {
+	return s.sleepEnabled
+}
+
+//SetSleepThreshold mets les seuils de sommeil: un îlot s'endort quand toutes ses formes
+// restent sous la vitesse velocity pendant ticks pas consécutifs
+func (s *Space) SetSleepThreshold(velocity float64, ticks float64) {
+	s.sleepVelocity = velocity
+	s.sleepTime = ticks
+}
+
+//sleepThreshold retourne les seuils de sommeil, valeurs par défaut si non définis
+func (s *Space) sleepThreshold() (float64, float64) {
+	velocity, ticks := s.sleepVelocity, s.sleepTime
+	if velocity <= 0 {
+		velocity = defaultSleepVelocity
+	}
+	if ticks <= 0 {
+		ticks = defaultSleepTime
+	}
+	return velocity, ticks
+}
+
+//Islands retourne les îlots de formes mobiles: des groupes de formes
+// reliées par un contact au dernier pas ou par une contrainte.
+// Les contacts entre formes endormies ne sont plus testés: un îlot endormi garde
+// les liens qu'il avait en s'endormant. Les formes statiques et cinématiques ne font partie
+// d'aucun îlot et ne les relient pas entre eux
+func (s *Space) Islands() [][]Shape {
+	index := map[Shape]int{}
+	mobile := []Shape{}
+	for _, shape := range s.shapesList {
+		if !shape.IsStatic() && !shape.IsKinematic() {
+			index[shape] = len(mobile)
+			mobile = append(mobile, shape)
+		}
+	}
+
+	parent := make([]int, len(mobile))
+	for i := range parent {
+		parent[i] = i
+	}
+
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	link := func(a Shape, b Shape) {
+		ia, okA := index[a]
+		ib, okB := index[b]
+		if okA && okB {
+			parent[find(ia)] = find(ib)
+		}
+	}
+
+	if s.collisions != nil {
+		for _, info := range s.collisions.GetAll() {
+			link(info.First(), info.Second())
+		}
+	}
+	for _, j := range s.joints {
+		if j.First() != nil && j.Second() != nil {
+			link(j.First(), j.Second())
+		}
+	}
+	for shape, island := range s.sleepIslands {
+		link(shape, island[0])
+	}
+
+	groups := map[int][]Shape{}
+	roots := []int{}
+	for i, shape := range mobile {
+		root := find(i)
+		if _, exists := groups[root]; !exists {
+			roots = append(roots, root)
+		}
+		groups[root] = append(groups[root], shape)
+	}
+
+	islands := make([][]Shape, 0, len(roots))
+	for _, root := range roots {
+		islands = append(islands, groups[root])
+	}
+	return islands
+}
+
+//updateSleep endort les îlots restés immobiles assez longtemps
+// et réveille entièrement les îlots dont une forme est éveillée.
+// Un îlot entraîné par une forme cinématique en mouvement ne dort pas, même lentement
+func (s *Space) updateSleep() {
+	if !s.sleepEnabled {
+		return
+	}
+
+	velocity, ticks := s.sleepThreshold()
+	driven := s.drivenShapes()
+
+	for _, island := range s.Islands() {
+		canSleep := true
+		awake := false
+
+		for _, shape := range island {
+			if !shape.IsSleeping() || driven[shape] {
+				awake = true
+			}
+			if driven[shape] {
+				canSleep = false
+			}
+			if sl, ok := shape.(sleeper); ok && sl.tickIdle(velocity) < ticks {
+				canSleep = false
+			}
+		}
+
+		for _, shape := range island {
+			if canSleep {
+				if s.sleepIslands == nil {
+					s.sleepIslands = map[Shape][]Shape{}
+				}
+				shape.Sleep()
+				s.sleepIslands[shape] = island
+			} else {
+				if awake && shape.IsSleeping() {
+					shape.Wake()
+				}
+				delete(s.sleepIslands, shape)
+			}
+		}
+	}
+}
+
+//drivenShapes retourne les formes qui touchaient au dernier pas une forme cinématique
+// en mouvement, qui lui sont liées par une contrainte ou qui se sont endormies dessus
+func (s *Space) drivenShapes() map[Shape]bool {
+	driven := map[Shape]bool{}
+	mark := func(a Shape, b Shape) {
+		if a != nil && b != nil && a.IsKinematic() && !resting(a) {
+			driven[b] = true
+		}
+	}
+
+	if s.collisions != nil {
+		for _, info := range s.collisions.GetAll() {
+			mark(info.First(), info.Second())
+			mark(info.Second(), info.First())
+		}
+	}
+	for _, j := range s.joints {
+		mark(j.First(), j.Second())
+		mark(j.Second(), j.First())
+	}
+	// le contact avec un support à l'arrêt n'est plus testé: il reste connu par Ground
+	for _, shape := range s.shapesList {
+		if shape.IsSleeping() {
+			mark(shape.Ground(), shape)
+		}
+	}
+	return driven
+}
+
+//wakeTouching réveille les formes endormies qui touchent la forme obj, avant son retrait:
+// le reste de leur îlot se réveille au pas suivant
+func (s *Space) wakeTouching(obj Shape) {
+	delete(s.sleepIslands, obj)
+
+	// marge pour les formes posées exactement au contact
+	const margin = 1
+	min := obj.Pos().Sub(Vec2{margin, margin})
+	max := obj.Pos().Add(Vec2{obj.Width() + margin, obj.Height() + margin})
+	for _, shape := range s.shapesList {
+		if shape == obj || !shape.IsSleeping() {
+			continue
+		}
+		pos := shape.Pos()
+		if pos.X <= max.X && pos.X+shape.Width() >= min.X && pos.Y <= max.Y && pos.Y+shape.Height() >= min.Y {
+			shape.Wake()
+		}
+	}
+}
+
+//resting retourne true si la forme ne bouge pas d'elle-même: statique, endormie,
+// ou cinématique à l'arrêt
+func resting(shape Shape) bool {
+	if shape.IsKinematic() && !shape.IsStatic() && !shape.IsSleeping() {
+		return shape.Velocity() == Vec2{}
+	}
+	return shape.IsStatic() || shape.IsSleeping()
+}
+
+//bothResting retourne true si aucune des deux formes ne peut bouger:
+// inutile de tester la collision
+func bothResting(first Shape, second Shape) bool {
+	return resting(first) && resting(second)
+}
+
+//wakeOnContact réveille la forme endormie touchée par une forme qui bouge
+func wakeOnContact(info *CollisionInfo) {
+	first, second := info.First(), info.Second()
+	if first.IsSleeping() && !resting(second) {
+		first.Wake()
+	}
+	if second.IsSleeping() && !resting(first) {
+		second.Wake()
+	}
+}
+
+//jointAwake retourne true si la contrainte lie au moins une forme qui bouge
+func jointAwake(j Joint) bool {
+	for _, shape := range []Shape{j.First(), j.Second()} {
+		if shape != nil && !resting(shape) {
+			return true
+		}
+	}
+	return false
+}
+
+//wakeJoint réveille les formes liées par la contrainte j, quand elle commence
+// à agir sur elles ou que ses réglages changent
+func wakeJoint(j Joint) {
+	for _, shape := range []Shape{j.First(), j.Second()} {
+		if shape != nil {
+			shape.Wake()
+		}
+	}
+}
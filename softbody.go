@@ -0,0 +1,624 @@
+package physics
+
+import "math"
+
+//SoftBody corps mou: points massiques reliés par des ressorts, intégrés par Verlet
+// en plusieurs sous-pas par tick pour que des ressorts raides restent stables.
+// Un corps fermé peut être gonflé par une pression qui rétablit son aire au repos.
+// Les points rebondissent sur les formes solides de l'espace et poussent les formes dynamiques
+type SoftBody struct {
+	x, y         []float64
+	prevX, prevY []float64
+	fx, fy       []float64
+	pinned       []bool
+	springs      []spring
+	perimeter    []int // indices des points du contour, dans l'ordre
+	closed       bool
+	restArea     float64
+	pointMass    float64
+	stiffness    float64
+	damping      float64
+	pressure     float64
+	bounce       float64
+	friction     float64
+	gravScale    float64
+	iterations   int
+}
+
+//spring ressort entre les points a et b
+type spring struct {
+	a, b int
+	rest float64
+}
+
+const (
+	defaultSoftStiffness  float64 = 1
+	defaultSoftDamping    float64 = 0.1
+	defaultSoftIterations int     = 8
+	defaultSoftPressure   float64 = 1
+	defaultSoftBounce     float64 = 0.2
+	defaultSoftFriction   float64 = 0.2
+)
+
+//newSoftBody crée un corps de mass répartie sur les points donnés, sans ressort
+func newSoftBody(points []Vec2, mass float64) *SoftBody {
+	b := &SoftBody{
+		pointMass: mass / float64(len(points)),
+		stiffness: defaultSoftStiffness,
+		damping:   defaultSoftDamping,
+		bounce:    defaultSoftBounce,
+		friction:  defaultSoftFriction,
+		gravScale: 1,
+	}
+	for _, p := range points {
+		b.x = append(b.x, p.X)
+		b.y = append(b.y, p.Y)
+	}
+	b.prevX = append([]float64{}, b.x...)
+	b.prevY = append([]float64{}, b.y...)
+	b.fx = make([]float64, len(points))
+	b.fy = make([]float64, len(points))
+	b.pinned = make([]bool, len(points))
+	return b
+}
+
+//NewBlob crée un blob: un anneau de count points autour de center, gonflé par la pression
+func NewBlob(center Vec2, radius float64, count int, mass float64) *SoftBody {
+	b := newRing(center, radius, count, mass)
+	b.pressure = defaultSoftPressure
+	return b
+}
+
+//NewRopeRing crée un anneau de corde de count points autour de center, sans pression:
+// il s'affaisse sous son poids
+func NewRopeRing(center Vec2, radius float64, count int, mass float64) *SoftBody {
+	return newRing(center, radius, count, mass)
+}
+
+//newRing crée un anneau fermé de count points (au moins 3)
+func newRing(center Vec2, radius float64, count int, mass float64) *SoftBody {
+	if count < 3 {
+		count = 3
+	}
+	points := make([]Vec2, count)
+	for i := range points {
+		a := 2 * math.Pi * float64(i) / float64(count)
+		points[i] = center.Add(Vec2{math.Cos(a), math.Sin(a)}.Mult(radius))
+	}
+
+	b := newSoftBody(points, mass)
+	for i := 0; i < count; i++ {
+		b.perimeter = append(b.perimeter, i)
+		b.addSpring(i, (i+1)%count)
+	}
+	b.closed = true
+	b.restArea = b.Area()
+	return b
+}
+
+//NewJellyBox crée une boîte de gelée: une grille de cols x rows points (au moins 2 x 2)
+// reliés par des ressorts structurels et de cisaillement. Sans pression par défaut
+func NewJellyBox(pos Vec2, width float64, height float64, cols int, rows int, mass float64) *SoftBody {
+	if cols < 2 {
+		cols = 2
+	}
+	if rows < 2 {
+		rows = 2
+	}
+	points := make([]Vec2, 0, cols*rows)
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			points = append(points, pos.Add(Vec2{
+				width * float64(c) / float64(cols-1),
+				height * float64(r) / float64(rows-1),
+			}))
+		}
+	}
+
+	b := newSoftBody(points, mass)
+	at := func(c, r int) int { return r*cols + c }
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			if c+1 < cols {
+				b.addSpring(at(c, r), at(c+1, r))
+			}
+			if r+1 < rows {
+				b.addSpring(at(c, r), at(c, r+1))
+			}
+			if c+1 < cols && r+1 < rows {
+				b.addSpring(at(c, r), at(c+1, r+1))
+				b.addSpring(at(c+1, r), at(c, r+1))
+			}
+		}
+	}
+
+	// contour dans l'ordre: haut, droite, bas, gauche
+	for c := 0; c < cols; c++ {
+		b.perimeter = append(b.perimeter, at(c, 0))
+	}
+	for r := 1; r < rows; r++ {
+		b.perimeter = append(b.perimeter, at(cols-1, r))
+	}
+	for c := cols - 2; c >= 0; c-- {
+		b.perimeter = append(b.perimeter, at(c, rows-1))
+	}
+	for r := rows - 2; r > 0; r-- {
+		b.perimeter = append(b.perimeter, at(0, r))
+	}
+	b.closed = true
+	b.restArea = b.Area()
+	return b
+}
+
+//addSpring relie les points a et b à leur distance actuelle
+func (b *SoftBody) addSpring(i int, j int) {
+	b.springs = append(b.springs, spring{i, j, b.Point(i).Distance(b.Point(j))})
+}
+
+//Count retourne le nombre de points
+func (b *SoftBody) Count() int {
+	return len(b.x)
+}
+
+//Point retourne la position du point i
+func (b *SoftBody) Point(i int) Vec2 {
+	return Vec2{b.x[i], b.y[i]}
+}
+
+//PointVelocity retourne la vitesse du point i, par tick
+func (b *SoftBody) PointVelocity(i int) Vec2 {
+	return b.step(i).Mult(float64(b.Iterations()))
+}
+
+//step retourne le déplacement du point i au dernier sous-pas
+func (b *SoftBody) step(i int) Vec2 {
+	return Vec2{b.x[i] - b.prevX[i], b.y[i] - b.prevY[i]}
+}
+
+//SetPoint déplace le point i en p, sans changer sa vitesse
+func (b *SoftBody) SetPoint(i int, p Vec2) {
+	v := b.step(i)
+	b.x[i], b.y[i] = p.X, p.Y
+	b.prevX[i], b.prevY[i] = p.X-v.X, p.Y-v.Y
+}
+
+//Iterations retourne le nombre de sous-pas par tick
+func (b *SoftBody) Iterations() int {
+	if b.iterations <= 0 {
+		return defaultSoftIterations
+	}
+	return b.iterations
+}
+
+//SetIterations mets le nombre de sous-pas par tick. 0 rétablit la valeur par défaut
+func (b *SoftBody) SetIterations(n int) {
+	// conserve la vitesse par tick des points
+	ratio := float64(b.Iterations())
+	b.iterations = n
+	ratio /= float64(b.Iterations())
+	for i := range b.x {
+		v := b.step(i).Mult(ratio)
+		b.prevX[i], b.prevY[i] = b.x[i]-v.X, b.y[i]-v.Y
+	}
+}
+
+//Pin fixe le point i (pinned à true) ou le libère
+func (b *SoftBody) Pin(i int, pinned bool) {
+	b.pinned[i] = pinned
+	if pinned {
+		b.prevX[i], b.prevY[i] = b.x[i], b.y[i]
+	}
+}
+
+//Perimeter retourne le contour du corps, dans l'ordre
+func (b *SoftBody) Perimeter() []Vec2 {
+	poly := make([]Vec2, len(b.perimeter))
+	for k, i := range b.perimeter {
+		poly[k] = b.Point(i)
+	}
+	return poly
+}
+
+//Area retourne l'aire délimitée par le contour (formule du lacet)
+func (b *SoftBody) Area() float64 {
+	return math.Abs(b.signedArea())
+}
+
+//RestArea retourne l'aire que la pression tend à rétablir
+func (b *SoftBody) RestArea() float64 {
+	return b.restArea
+}
+
+//SetRestArea mets l'aire que la pression tend à rétablir
+func (b *SoftBody) SetRestArea(a float64) {
+	b.restArea = a
+}
+
+//signedArea retourne l'aire signée du contour, positive si le contour tourne dans le sens direct
+func (b *SoftBody) signedArea() float64 {
+	area := 0.0
+	n := len(b.perimeter)
+	for k := 0; k < n; k++ {
+		i, j := b.perimeter[k], b.perimeter[(k+1)%n]
+		area += b.x[i]*b.y[j] - b.x[j]*b.y[i]
+	}
+	return area / 2
+}
+
+//Center retourne le centre de masse du corps
+func (b *SoftBody) Center() Vec2 {
+	c := Vec2{}
+	for i := range b.x {
+		c = c.Add(b.Point(i))
+	}
+	return c.Div(float64(len(b.x)))
+}
+
+//Mass retourne la masse totale du corps
+func (b *SoftBody) Mass() float64 {
+	return b.pointMass * float64(len(b.x))
+}
+
+//Stiffness retourne la raideur des ressorts
+func (b *SoftBody) Stiffness() float64 {
+	return b.stiffness
+}
+
+//SetStiffness mets la raideur des ressorts, rapportée à la masse d'un point
+// Une raideur élevée demande plus de sous-pas (voir SetIterations)
+func (b *SoftBody) SetStiffness(k float64) {
+	b.stiffness = k
+}
+
+//Damping retourne l'amortissement des ressorts
+func (b *SoftBody) Damping() float64 {
+	return b.damping
+}
+
+//SetDamping mets l'amortissement des ressorts, rapporté à la masse d'un point
+func (b *SoftBody) SetDamping(c float64) {
+	b.damping = c
+}
+
+//Pressure retourne la pression du corps
+func (b *SoftBody) Pressure() float64 {
+	return b.pressure
+}
+
+//SetPressure mets la pression qui rétablit l'aire au repos, 0 pour aucune
+// N'a d'effet que sur un corps fermé
+func (b *SoftBody) SetPressure(p float64) {
+	b.pressure = p
+}
+
+//Bounce retourne la part de la vitesse normale conservée au rebond
+func (b *SoftBody) Bounce() float64 {
+	return b.bounce
+}
+
+//SetBounce mets la part de la vitesse normale conservée au rebond, entre 0 et 1
+func (b *SoftBody) SetBounce(e float64) {
+	b.bounce = Clamp(e, 0, 1)
+}
+
+//Friction retourne la part de la vitesse tangentielle perdue au contact
+func (b *SoftBody) Friction() float64 {
+	return b.friction
+}
+
+//SetFriction mets la part de la vitesse tangentielle perdue au contact, entre 0 et 1
+func (b *SoftBody) SetFriction(f float64) {
+	b.friction = Clamp(f, 0, 1)
+}
+
+//GravityScale retourne le facteur appliqué à la gravité du corps
+func (b *SoftBody) GravityScale() float64 {
+	return b.gravScale
+}
+
+//SetGravityScale mets le facteur appliqué à la gravité du corps
+func (b *SoftBody) SetGravityScale(g float64) {
+	b.gravScale = g
+}
+
+//ApplyImpulse change la vitesse de tous les points de j divisé par la masse totale
+func (b *SoftBody) ApplyImpulse(j Vec2) {
+	dv := j.Div(b.Mass() * float64(b.Iterations()))
+	for i := range b.x {
+		if !b.pinned[i] {
+			b.prevX[i] -= dv.X
+			b.prevY[i] -= dv.Y
+		}
+	}
+}
+
+//accumulateSprings ajoute les forces des ressorts, rapportées à la masse d'un point
+func (b *SoftBody) accumulateSprings() {
+	for _, sp := range b.springs {
+		d := b.Point(sp.b).Sub(b.Point(sp.a))
+		length := d.Length()
+		if length == 0 {
+			continue
+		}
+		dir := d.Div(length)
+		relVel := b.PointVelocity(sp.b).Sub(b.PointVelocity(sp.a)).DotProduct(dir)
+		f := dir.Mult(b.stiffness*(length-sp.rest) + b.damping*relVel)
+
+		b.fx[sp.a] += f.X
+		b.fy[sp.a] += f.Y
+		b.fx[sp.b] -= f.X
+		b.fy[sp.b] -= f.Y
+	}
+}
+
+//accumulatePressure ajoute la pression sur chaque arête du contour, le long de sa
+// normale sortante. Elle est nulle à l'aire au repos, pousse vers l'extérieur
+// quand le corps est écrasé et tire vers l'intérieur quand il est étiré
+func (b *SoftBody) accumulatePressure() {
+	area := b.signedArea()
+	if !b.closed || b.pressure == 0 || area == 0 {
+		return
+	}
+	p := b.pressure * (b.restArea/math.Abs(area) - 1)
+	// l'orientation du contour donne le sens de la normale sortante
+	orient := Sign(area)
+
+	n := len(b.perimeter)
+	for k := 0; k < n; k++ {
+		i, j := b.perimeter[k], b.perimeter[(k+1)%n]
+		// normale sortante de longueur égale à l'arête
+		normal := Vec2{b.y[j] - b.y[i], -(b.x[j] - b.x[i])}.Mult(orient)
+		f := normal.Mult(p / 2)
+
+		b.fx[i] += f.X
+		b.fy[i] += f.Y
+		b.fx[j] += f.X
+		b.fy[j] += f.Y
+	}
+}
+
+//update intègre le corps d'un tick, en sous-pas, et le fait rebondir sur obstacles
+func (b *SoftBody) update(s *Space, obstacles []obstacle) {
+	n := b.Iterations()
+	for k := 0; k < n; k++ {
+		b.substep(s, obstacles, 1/float64(n))
+	}
+}
+
+//substep intègre le corps sur dt ticks
+func (b *SoftBody) substep(s *Space, obstacles []obstacle, dt float64) {
+	for i := range b.fx {
+		b.fx[i], b.fy[i] = 0, 0
+	}
+	b.accumulateSprings()
+	b.accumulatePressure()
+
+	g := s.gravity.Mult(b.gravScale)
+	for i := range b.x {
+		if b.pinned[i] {
+			continue
+		}
+		pos, prev := b.Point(i), Vec2{b.prevX[i], b.prevY[i]}
+		if len(s.attractors) > 0 {
+			g = s.gravityAt(pos, nil).Mult(b.gravScale)
+		}
+
+		accel := g.Add(Vec2{b.fx[i], b.fy[i]})
+		next := pos.Add(pos.Sub(prev)).Add(accel.Mult(dt * dt))
+		start := pos
+		prev = pos
+		pos = next
+
+		// les formes dynamiques reçoivent la quantité de mouvement perdue par le point
+		pos, prev = resolvePoint(pos, prev, start, dt, obstacles, b.bounce, b.friction, b.pointMass)
+
+		b.x[i], b.y[i] = pos.X, pos.Y
+		b.prevX[i], b.prevY[i] = prev.X, prev.Y
+	}
+
+	b.collideEdges(obstacles, dt)
+}
+
+//collideEdges empêche les formes plus fines que l'écart entre deux points de passer
+// entre eux: chaque arête du contour qui coupe une forme en est repoussée
+func (b *SoftBody) collideEdges(obstacles []obstacle, dt float64) {
+	n := len(b.perimeter)
+	if !b.closed {
+		n--
+	}
+	// l'orientation du contour donne le côté intérieur des arêtes
+	orient := Sign(b.signedArea())
+	for k := 0; k < n; k++ {
+		i, j := b.perimeter[k], b.perimeter[(k+1)%len(b.perimeter)]
+		for _, o := range obstacles {
+			a, c := b.Point(i), b.Point(j)
+			if math.Max(a.X, c.X) < o.min.X || math.Min(a.X, c.X) >= o.max.X ||
+				math.Max(a.Y, c.Y) < o.min.Y || math.Min(a.Y, c.Y) >= o.max.Y {
+				continue
+			}
+			inward := Vec2{c.Y - a.Y, a.X - c.X}.Normalize().Mult(-orient)
+			if !b.closed {
+				inward = Vec2{}
+			}
+
+			var t, depth float64
+			var dir Vec2
+			var hit bool
+			if circ, ok := o.shape.(*Circle); ok {
+				t, dir, depth, hit = edgeCircleOverlap(a, c, inward, circ.Center(), circ.Radius())
+			} else {
+				t, dir, depth, hit = edgeRectOverlap(a, c, inward, o.min, o.max)
+			}
+			if hit {
+				b.pushEdge(i, j, t, dir.Mult(depth+pointSkin), o.shape, dt)
+			}
+		}
+	}
+}
+
+//pushEdge déplace l'arête i, j pour que son point en t se déplace de move.
+// Le déplacement est réparti selon le bras de levier, les points fixés ne bougent pas.
+// Il ne crée pas de vitesse: seule la vitesse des points vers la forme est annulée,
+// et une forme dynamique reçoit la quantité de mouvement perdue par les points
+func (b *SoftBody) pushEdge(i int, j int, t float64, move Vec2, shape Shape, dt float64) {
+	wi, wj := 1.0, 1.0
+	if b.pinned[i] {
+		wi = 0
+	}
+	if b.pinned[j] {
+		wj = 0
+	}
+	sum := wi*(1-t)*(1-t) + wj*t*t
+	if sum == 0 {
+		return
+	}
+	dir := move.Normalize()
+	lost := Vec2{}
+	for _, pm := range [2]struct {
+		k int
+		m Vec2
+	}{{i, move.Mult(wi * (1 - t) / sum)}, {j, move.Mult(wj * t / sum)}} {
+		if pm.m == (Vec2{}) {
+			continue
+		}
+		k := pm.k
+		b.x[k], b.y[k] = b.x[k]+pm.m.X, b.y[k]+pm.m.Y
+		v := Vec2{b.x[k] - b.prevX[k] - pm.m.X, b.y[k] - b.prevY[k] - pm.m.Y}
+		if vn := v.DotProduct(dir); vn < 0 {
+			lost = lost.Add(dir.Mult(vn))
+			v = v.Sub(dir.Mult(vn))
+		}
+		b.prevX[k], b.prevY[k] = b.x[k]-v.X, b.y[k]-v.Y
+	}
+
+	if shape.InvMass() > 0 {
+		shape.ApplyImpulse(lost.Mult(b.pointMass / dt))
+	}
+}
+
+//edgeRectOverlap teste le segment a, c contre le rectangle min, max.
+// S'ils se coupent, retourne le paramètre t du point du segment à déplacer, la direction
+// et la profondeur du déplacement. Une forme entre dans le corps par l'extérieur:
+// si des coins du rectangle dépassent l'arête côté inward (normale intérieure, nulle
+// si inconnue), l'arête recule vers l'intérieur jusqu'au plus enfoncé. Sinon, comme
+// pour un sol plus large que l'arête, le plus petit déplacement la sort du rectangle
+func edgeRectOverlap(a Vec2, c Vec2, inward Vec2, min Vec2, max Vec2) (float64, Vec2, float64, bool) {
+	// portion du segment dans le rectangle
+	d := c.Sub(a)
+	t0, t1 := 0.0, 1.0
+	slabs := [2]struct{ from, d, min, max float64 }{
+		{a.X, d.X, min.X, max.X},
+		{a.Y, d.Y, min.Y, max.Y},
+	}
+	for _, sl := range slabs {
+		if sl.d == 0 {
+			if sl.from < sl.min || sl.from >= sl.max {
+				return 0, Vec2{}, 0, false
+			}
+			continue
+		}
+		e1, e2 := (sl.min-sl.from)/sl.d, (sl.max-sl.from)/sl.d
+		if e1 > e2 {
+			e1, e2 = e2, e1
+		}
+		t0, t1 = math.Max(t0, e1), math.Min(t1, e2)
+	}
+	if t0 >= t1 {
+		return 0, Vec2{}, 0, false
+	}
+
+	if inward != (Vec2{}) {
+		length2 := d.DotProduct(d)
+		deepest, deepestT := 0.0, 0.0
+		for _, q := range [4]Vec2{min, {max.X, min.Y}, max, {min.X, max.Y}} {
+			rel := q.Sub(a)
+			t := rel.DotProduct(d) / length2
+			if depth := rel.DotProduct(inward); t >= 0 && t <= 1 && depth > deepest {
+				deepest, deepestT = depth, t
+			}
+		}
+		if deepest > 0 {
+			return deepestT, inward, deepest, true
+		}
+	}
+
+	// profondeur d'un point sous la face de normale dir
+	depthTo := func(p Vec2, dir Vec2) float64 {
+		switch dir {
+		case Vec2{-1, 0}:
+			return p.X - min.X
+		case Vec2{1, 0}:
+			return max.X - p.X
+		case Vec2{0, -1}:
+			return p.Y - min.Y
+		default:
+			return max.Y - p.Y
+		}
+	}
+
+	// la portion sort par la face qui demande le plus petit déplacement de son point
+	// le plus enfoncé
+	p0, p1 := a.Add(d.Mult(t0)), a.Add(d.Mult(t1))
+	best, bestT, bestDir := math.Inf(1), 0.0, Vec2{}
+	for _, dir := range [4]Vec2{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+		depth, t := depthTo(p0, dir), t0
+		if d1 := depthTo(p1, dir); d1 > depth {
+			depth, t = d1, t1
+		}
+		if depth < best {
+			best, bestT, bestDir = depth, t, dir
+		}
+	}
+	return bestT, bestDir, best, best > 0
+}
+
+//edgeCircleOverlap teste le segment a, c contre le cercle de centre center et de rayon r.
+// S'ils se coupent, retourne le paramètre t du point du segment le plus proche du centre,
+// la direction et la profondeur qui l'amènent sur le cercle. Si le centre a dépassé
+// l'arête côté inward (normale intérieure, nulle si inconnue), l'arête recule vers
+// l'intérieur au-delà du cercle
+func edgeCircleOverlap(a Vec2, c Vec2, inward Vec2, center Vec2, r float64) (float64, Vec2, float64, bool) {
+	d := c.Sub(a)
+	length2 := d.DotProduct(d)
+	if length2 == 0 {
+		return 0, Vec2{}, 0, false
+	}
+	t := Clamp(center.Sub(a).DotProduct(d)/length2, 0, 1)
+	closest := a.Add(d.Mult(t))
+	toEdge := closest.Sub(center)
+	dist := toEdge.Length()
+	if dist >= r {
+		return 0, Vec2{}, 0, false
+	}
+
+	if past := center.Sub(closest).DotProduct(inward); inward != (Vec2{}) && past > 0 {
+		return t, inward, past + r, true
+	}
+	dir := Vec2{-d.Y, d.X}.Normalize()
+	if dist > 0 {
+		dir = toEdge.Div(dist)
+	}
+	return t, dir, r - dist, true
+}
+
+//SoftBodies retourne la liste des corps mous de l'espace
+func (s *Space) SoftBodies() []*SoftBody {
+	return s.softBodies
+}
+
+//AddSoftBody ajoute un corps mou à l'espace
+func (s *Space) AddSoftBody(b *SoftBody) {
+	s.softBodies = append(s.softBodies, b)
+}
+
+//RemoveSoftBody supprime un corps mou de l'espace
+func (s *Space) RemoveSoftBody(b *SoftBody) {
+	for i, body := range s.softBodies {
+		if body == b {
+			copy(s.softBodies[i:], s.softBodies[i+1:])
+			s.softBodies[len(s.softBodies)-1] = nil
+			s.softBodies = s.softBodies[:len(s.softBodies)-1]
+			return
+		}
+	}
+}
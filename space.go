@@ -1,19 +1,64 @@
 package physics
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+)
 
 //Space Contient toutes les shape
 type Space struct {
-	shapesList []Shape
-	collisions *InfoList
-	dt         float64
-	gravity    Vec2
+	shapesList      []Shape
+	joints          []Joint
+	effectors       []*AreaEffector
+	fluids          []*FluidVolume
+	attractors      []*Attractor
+	materials       *MaterialLibrary
+	maxGroundAngle  float64
+	releases        map[Shape]release
+	actors          []*Actor
+	solids          []*Solid
+	crushes         []*CrushEvent
+	onCrush         func(*CrushEvent)
+	crushDepth      float64
+	emitters        []*ParticleEmitter
+	softBodies      []*SoftBody
+	sphFluids       []*SPHFluid
+	jointIterations int
+	collisions      *InfoList
+	dt              float64
+	gravity         Vec2
+	linearDrag      float64
+	quadDrag        float64
+	sleepEnabled    bool
+	sleepVelocity   float64
+	sleepTime       float64
+	sleepIslands    map[Shape][]Shape
 }
 
-//Update met l'espace à jour: positions et collisions
+const (
+	defaultJointIterations int     = 4
+	defaultSleepVelocity   float64 = 0.05
+	defaultSleepTime       float64 = 60
+	defaultMaxGroundAngle  float64 = math.Pi / 4
+)
+
+//Update met l'espace à jour: sommeil, forces, positions, contraintes, particules,
+// fluides SPH, collisions et écrasements
 func (s *Space) Update() {
+	s.crushes = nil
+	s.updateSleep()
+	s.ApplyGravity()
+	s.applyEffectors()
+	s.applyFluids()
+	s.solveJointVelocities()
+	riders := s.riders()
 	s.updatePositions()
+	s.carryRiders(riders)
+	s.solveJointPositions()
+	s.updateParticles()
 	s.checkCollisions()
+	s.detectCrushes()
+	s.releaseRiders(riders)
 }
 
 //Collisions retourne la liste des collisions
@@ -40,21 +85,244 @@ func (s *Space) RemoveShape(obj Shape) {
 	}
 
 	if i < len(s.shapesList) {
+		// les formes endormies qui reposaient sur elle doivent retomber
+		s.wakeTouching(s.shapesList[i])
+
 		if i < len(s.shapesList)-1 {
 			copy(s.shapesList[i:], s.shapesList[i+1:])
 		}
 		s.shapesList[len(s.shapesList)-1] = nil
 		s.shapesList = s.shapesList[:len(s.shapesList)-1]
 	}
+
+	// supprime les contraintes qui impliquent la forme
+	s.filterJoints(func(j Joint) bool {
+		return !jointInvolves(j, obj)
+	})
+}
+
+//Joints retourne la liste des contraintes de l'espace
+func (s *Space) Joints() []Joint {
+	return s.joints
+}
+
+//AddJoint ajoute une contrainte à l'espace et réveille les formes qu'elle lie
+func (s *Space) AddJoint(j Joint) {
+	s.joints = append(s.joints, j)
+	wakeJoint(j)
+}
+
+//RemoveJoint supprime une contrainte de l'espace et réveille les formes qu'elle liait
+func (s *Space) RemoveJoint(j Joint) {
+	for i, joint := range s.joints {
+		if joint == j {
+			wakeJoint(j)
+			copy(s.joints[i:], s.joints[i+1:])
+			s.joints[len(s.joints)-1] = nil
+			s.joints = s.joints[:len(s.joints)-1]
+			return
+		}
+	}
+}
+
+//SetJointIterations mets le nombre d'itérations du solveur de contraintes à n
+// Plus d'itérations rend les chaînes de contraintes plus rigides
+func (s *Space) SetJointIterations(n int) {
+	s.jointIterations = n
+}
+
+//velocityRelaxer est implémentée par les contraintes dont la partie vitesse
+// se résout en plusieurs passes, comme les positions
+type velocityRelaxer interface {
+	relaxVelocity()
+}
+
+//solveJointVelocities applique la partie vitesse des contraintes, avant intégration
+// Les contraintes qui le permettent sont résolues plusieurs fois pour converger
+func (s *Space) solveJointVelocities() {
+	for _, j := range s.joints {
+		if jointAwake(j) {
+			j.SolveVelocity()
+		}
+	}
+
+	for it := 1; it < s.jointIterationCount(); it++ {
+		for _, j := range s.joints {
+			if r, ok := j.(velocityRelaxer); ok && jointAwake(j) {
+				r.relaxVelocity()
+			}
+		}
+	}
+}
+
+//jointIterationCount retourne le nombre d'itérations du solveur de contraintes,
+// la valeur par défaut si non défini
+func (s *Space) jointIterationCount() int {
+	if s.jointIterations <= 0 {
+		return defaultJointIterations
+	}
+	return s.jointIterations
+}
+
+//solveJointPositions corrige les positions après intégration
+// Les contraintes sont résolues plusieurs fois pour converger
+func (s *Space) solveJointPositions() {
+	iterations := s.jointIterationCount()
+
+	for it := 0; it < iterations; it++ {
+		for _, j := range s.joints {
+			if jointAwake(j) {
+				j.SolvePosition()
+			}
+		}
+	}
+
+	s.removeBrokenJoints()
+}
+
+//breakable est implémentée par les contraintes qui peuvent rompre
+type breakable interface {
+	Broken() bool
+}
+
+//removeBrokenJoints supprime les contraintes rompues
+func (s *Space) removeBrokenJoints() {
+	s.filterJoints(func(j Joint) bool {
+		b, ok := j.(breakable)
+		return !ok || !b.Broken()
+	})
+}
+
+//filterJoints ne garde que les contraintes pour lesquelles keep retourne true
+// Les formes liées par une contrainte retirée sont réveillées
+func (s *Space) filterJoints(keep func(Joint) bool) {
+	joints := s.joints[:0]
+	for _, j := range s.joints {
+		if keep(j) {
+			joints = append(joints, j)
+		} else {
+			wakeJoint(j)
+		}
+	}
+	for k := len(joints); k < len(s.joints); k++ {
+		s.joints[k] = nil
+	}
+	s.joints = joints
+}
+
+//jointInvolves retourne true si la contrainte lie la forme obj
+func jointInvolves(j Joint, obj Shape) bool {
+	return (j.First() != nil && j.First().Name() == obj.Name()) ||
+		(j.Second() != nil && j.Second().Name() == obj.Name())
 }
 
 //updatePositions met à jour les positions des formes
+// Les formes endormies ne sont pas déplacées
 func (s *Space) updatePositions() {
-	for _, s := range s.shapesList {
-		if !s.IsStatic() {
-			s.UpdatePos()
+	for _, shape := range s.shapesList {
+		if !shape.IsStatic() && !shape.IsSleeping() {
+			s.applyDrag(shape)
+			shape.UpdatePos()
+		}
+	}
+}
+
+//rider une forme mobile et son support au début du pas
+type rider struct {
+	shape     Shape
+	ground    Shape
+	groundPos Vec2
+	normal    Vec2 //normale du sol, du support vers la forme
+}
+
+//riders retourne les formes dynamiques avec leur support et la position
+// du support avant déplacement. Une forme cinématique n'est pas emportée
+func (s *Space) riders() []rider {
+	riders := []rider{}
+	for _, shape := range s.shapesList {
+		if shape.IsStatic() || shape.IsKinematic() || shape.IsSleeping() {
+			continue
+		}
+		r := rider{shape: shape, ground: shape.Ground(), normal: shape.GroundNormal()}
+		if movingGround(r.ground) {
+			r.groundPos = r.ground.Pos()
+		}
+		riders = append(riders, r)
+	}
+	return riders
+}
+
+//movingGround retourne true si ground est un support qui peut bouger
+func movingGround(ground Shape) bool {
+	return ground != nil && !ground.IsStatic()
+}
+
+//carried retourne la part du mouvement move du support que la forme reçoit de lui.
+// Un support cinématique emporte la forme entièrement. Les contacts avec un support
+// dynamique se résolvent avec les vitesses réelles (voir relativeVelocity): il n'emporte
+// la forme que le long du contact, sans quoi elle tomberait aussi de son pas de gravité
+func (r rider) carried(move Vec2) Vec2 {
+	if r.ground.IsKinematic() {
+		return move
+	}
+	return move.Sub(r.normal.Mult(move.DotProduct(r.normal)))
+}
+
+//carryRiders déplace chaque forme portée du déplacement de son support pendant le pas
+func (s *Space) carryRiders(riders []rider) {
+	for _, r := range riders {
+		if movingGround(r.ground) {
+			delta := r.carried(r.ground.Pos().Sub(r.groundPos))
+			r.shape.SetPos(r.shape.Pos().Add(delta))
+		}
+	}
+}
+
+//release vitesse donnée à une forme qui a quitté son support au dernier pas
+type release struct {
+	ground   Shape
+	velocity Vec2
+}
+
+//releaseRiders donne la vitesse qu'emportait le support aux formes qui l'ont quitté,
+// pour qu'un saut depuis une plateforme conserve son élan. Une forme qui retrouve
+// au pas suivant le support qu'elle vient de quitter la rend: une perte de contact
+// d'un seul pas ne crée pas d'énergie
+func (s *Space) releaseRiders(riders []rider) {
+	previous := s.releases
+	s.releases = nil
+	for _, r := range riders {
+		ground := r.shape.Ground()
+		if ground == r.ground {
+			continue
+		}
+		if rel, ok := previous[r.shape]; ok && rel.ground == ground {
+			r.shape.SetVelocity(r.shape.Velocity().Sub(rel.velocity))
 		}
+		if movingGround(r.ground) {
+			v := r.carried(r.ground.Velocity())
+			r.shape.SetVelocity(r.shape.Velocity().Add(v))
+			if s.releases == nil {
+				s.releases = map[Shape]release{}
+			}
+			s.releases[r.shape] = release{ground: r.ground, velocity: v}
+		}
+	}
+}
+
+//MaxGroundAngle retourne l'angle maximal (radians) d'un sol sur lequel une forme est au sol
+func (s *Space) MaxGroundAngle() float64 {
+	if s.maxGroundAngle <= 0 {
+		return defaultMaxGroundAngle
 	}
+	return s.maxGroundAngle
+}
+
+//SetMaxGroundAngle mets l'angle maximal d'un sol praticable à a (radians)
+// L'angle est mesuré entre la normale du contact et l'opposé de la gravité de la forme.
+// 0 rétablit la valeur par défaut, 45°
+func (s *Space) SetMaxGroundAngle(a float64) {
+	s.maxGroundAngle = a
 }
 
 //SetGravity mets la gravité de l'espace à g
@@ -62,24 +330,37 @@ func (s *Space) SetGravity(g Vec2) {
 	s.gravity = g
 }
 
-//ApplyGravity met la gravité de tous les éléments de l'espace
-// à la gravité de l'espace
+//spaceGravity est implémentée par les formes qui reçoivent la gravité de l'espace
+type spaceGravity interface {
+	setSpaceGravity(g Vec2)
+}
+
+//ApplyGravity met la gravité des éléments de l'espace à la gravité locale (voir GravityAt).
+// Les formes qui ont leur propre gravité (SetGravity) la gardent. Appelée à chaque Update:
+// un changement de gravité de l'espace se propage sans appel manuel
 func (s *Space) ApplyGravity() {
 	for _, shape := range s.shapesList {
-		shape.SetGravity(s.gravity)
+		if sg, ok := shape.(spaceGravity); ok && shape.UsesSpaceGravity() {
+			sg.setSpaceGravity(s.GravityAt(shape))
+		}
 	}
 }
 
 //checkCollisions retourne la liste de toutes les collisions
 func (s *Space) checkCollisions() {
 	collisions := newInfoList()
+	groundCos := math.Cos(s.MaxGroundAngle())
 
 	for i := 0; i < len(s.shapesList)-1; i++ {
 		if s.shapesList[i].IsSolid() { // Ne check pas les formes qui ne collisionnent pas
 			for j := i + 1; j < len(s.shapesList); j++ {
-				if s.shapesList[j].IsSolid() {
+				if s.shapesList[j].IsSolid() && !bothResting(s.shapesList[i], s.shapesList[j]) {
 					info := s.dispatchCollisionCheck(s.shapesList[i], s.shapesList[j])
 					if info.IsColliding() {
+						wakeOnContact(info)
+						setContactSides(info, groundCos)
+						info.restitution, info.friction = s.Materials().Mix(info.first, info.second)
+						info.mixed = true
 						collisions.Add(info)
 					}
 				}
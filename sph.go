@@ -0,0 +1,440 @@
+package physics
+
+import "math"
+
+//SPHFluid fluide simulé par hydrodynamique des particules lissées (SPH)
+// Chaque particule porte densité et pression calculées sur ses voisines, trouvées
+// par une grille de cellules de la taille du rayon de lissage. Les particules sont
+// contenues par les formes solides de l'espace et poussent les formes dynamiques.
+// Une particule représente un carré de fluide de côté Spacing et de densité 1,
+// comme les formes par défaut: une forme moins dense flotte
+type SPHFluid struct {
+	x, y         []float64
+	vx, vy       []float64
+	fx, fy       []float64
+	density      []float64
+	pressure     []float64
+	grid         map[cellKey][]int
+	neighbors    []neighbor
+	nbrStart     []int
+	h            float64
+	poly6K       float64
+	spikyK       float64
+	viscK        float64
+	mass         float64
+	restDensity  float64
+	stiffness    float64
+	viscosity    float64
+	bounce       float64
+	friction     float64
+	gravScale    float64
+	iterations   int
+	substeps     int //sous-pas du dernier tick
+	maxParticles int
+}
+
+//cellKey coordonnées d'une cellule de la grille de voisinage
+type cellKey struct {
+	x, y int
+}
+
+//neighbor voisine j d'une particule, à l'écart d (de j vers la particule) et à la distance r
+type neighbor struct {
+	j int
+	d Vec2
+	r float64
+}
+
+const (
+	defaultSPHStiffness  float64 = 1000
+	defaultSPHViscosity  float64 = 5
+	defaultSPHBounce     float64 = 0.1
+	defaultSPHFriction   float64 = 0.05
+	defaultSPHIterations int     = 4
+	maxSPHIterations     int     = 64
+	// sous-pas automatiques: une onde de pression, de vitesse sqrt(stiffness),
+	// parcourt au plus sphCFL*h par sous-pas, et une particule au plus sphMaxTravel*h
+	sphCFL       float64 = 1
+	sphMaxTravel float64 = 0.5
+)
+
+//NewSPHFluid crée un fluide vide de rayon de lissage h
+// Les particules sont espacées de h/2 au repos
+func NewSPHFluid(h float64) *SPHFluid {
+	f := &SPHFluid{
+		grid:      map[cellKey][]int{},
+		h:         h,
+		poly6K:    4 / (math.Pi * math.Pow(h, 8)),
+		spikyK:    -30 / (math.Pi * math.Pow(h, 5)),
+		viscK:     40 / (math.Pi * math.Pow(h, 5)),
+		mass:      h * h / 4,
+		stiffness: defaultSPHStiffness,
+		viscosity: defaultSPHViscosity,
+		bounce:    defaultSPHBounce,
+		friction:  defaultSPHFriction,
+		gravScale: 1,
+	}
+	f.restDensity = f.latticeDensity()
+	return f
+}
+
+//latticeDensity retourne la densité d'une particule dans un réseau carré au pas Spacing
+func (f *SPHFluid) latticeDensity() float64 {
+	d := 0.0
+	n := int(math.Ceil(f.h / f.Spacing()))
+	for i := -n; i <= n; i++ {
+		for j := -n; j <= n; j++ {
+			r := Vec2{float64(i), float64(j)}.Mult(f.Spacing()).Length()
+			d += f.mass * f.poly6(r*r)
+		}
+	}
+	return d
+}
+
+//SmoothingRadius retourne le rayon de lissage
+func (f *SPHFluid) SmoothingRadius() float64 {
+	return f.h
+}
+
+//Spacing retourne l'écart entre particules au repos
+func (f *SPHFluid) Spacing() float64 {
+	return f.h / 2
+}
+
+//RestDensity retourne la densité au repos
+func (f *SPHFluid) RestDensity() float64 {
+	return f.restDensity
+}
+
+//SetRestDensity mets la densité au repos. Par défaut, celle du réseau au pas Spacing
+func (f *SPHFluid) SetRestDensity(d float64) {
+	f.restDensity = d
+}
+
+//Stiffness retourne la raideur du fluide
+func (f *SPHFluid) Stiffness() float64 {
+	return f.stiffness
+}
+
+//SetStiffness mets la raideur qui convertit l'excès de densité en pression
+// Plus elle est grande, moins le fluide se comprime, et plus il faut de sous-pas
+// (voir SetIterations)
+func (f *SPHFluid) SetStiffness(k float64) {
+	f.stiffness = k
+}
+
+//Viscosity retourne la viscosité du fluide
+func (f *SPHFluid) Viscosity() float64 {
+	return f.viscosity
+}
+
+//SetViscosity mets la viscosité du fluide
+func (f *SPHFluid) SetViscosity(mu float64) {
+	f.viscosity = mu
+}
+
+//Bounce retourne la part de la vitesse normale conservée au rebond sur une forme
+func (f *SPHFluid) Bounce() float64 {
+	return f.bounce
+}
+
+//SetBounce mets la part de la vitesse normale conservée au rebond, entre 0 et 1
+func (f *SPHFluid) SetBounce(b float64) {
+	f.bounce = Clamp(b, 0, 1)
+}
+
+//Friction retourne la part de la vitesse tangentielle perdue contre une forme
+func (f *SPHFluid) Friction() float64 {
+	return f.friction
+}
+
+//SetFriction mets la part de la vitesse tangentielle perdue contre une forme, entre 0 et 1
+func (f *SPHFluid) SetFriction(fr float64) {
+	f.friction = Clamp(fr, 0, 1)
+}
+
+//GravityScale retourne le facteur appliqué à la gravité du fluide
+func (f *SPHFluid) GravityScale() float64 {
+	return f.gravScale
+}
+
+//SetGravityScale mets le facteur appliqué à la gravité du fluide
+func (f *SPHFluid) SetGravityScale(g float64) {
+	f.gravScale = g
+}
+
+//Iterations retourne le nombre de sous-pas par tick: celui fixé par SetIterations,
+// sinon celui calculé au dernier tick
+func (f *SPHFluid) Iterations() int {
+	if f.iterations > 0 {
+		return f.iterations
+	}
+	if f.substeps > 0 {
+		return f.substeps
+	}
+	return defaultSPHIterations
+}
+
+//SetIterations fixe le nombre de sous-pas par tick. 0 rétablit le calcul automatique,
+// qui suit la raideur, le rayon de lissage et la vitesse des particules
+func (f *SPHFluid) SetIterations(n int) {
+	f.iterations = n
+}
+
+//substepCount retourne le nombre de sous-pas du tick. Le pas stable d'Euler symplectique
+// décroît avec h et croît avec la raideur: sans valeur fixée, il est déduit de la vitesse
+// des ondes de pression et de la plus grande vitesse des particules, au moins
+// defaultSPHIterations et au plus maxSPHIterations
+func (f *SPHFluid) substepCount() int {
+	if f.iterations > 0 {
+		return f.iterations
+	}
+	maxSpeed2 := 0.0
+	for i := range f.vx {
+		maxSpeed2 = math.Max(maxSpeed2, f.vx[i]*f.vx[i]+f.vy[i]*f.vy[i])
+	}
+	n := math.Max(math.Sqrt(math.Max(f.stiffness, 0))/(sphCFL*f.h), math.Sqrt(maxSpeed2)/(sphMaxTravel*f.h))
+	return int(Clamp(math.Ceil(n), float64(defaultSPHIterations), float64(maxSPHIterations)))
+}
+
+//MaxParticles retourne le nombre maximum de particules, 0 si illimité
+func (f *SPHFluid) MaxParticles() int {
+	return f.maxParticles
+}
+
+//SetMaxParticles mets le nombre maximum de particules, 0 pour illimité
+func (f *SPHFluid) SetMaxParticles(n int) {
+	f.maxParticles = n
+}
+
+//Emit ajoute une particule en pos avec la vitesse vel (par tick)
+// Retourne false si le fluide est plein
+func (f *SPHFluid) Emit(pos Vec2, vel Vec2) bool {
+	if f.maxParticles > 0 && len(f.x) >= f.maxParticles {
+		return false
+	}
+	f.x = append(f.x, pos.X)
+	f.y = append(f.y, pos.Y)
+	f.vx = append(f.vx, vel.X)
+	f.vy = append(f.vy, vel.Y)
+	f.fx = append(f.fx, 0)
+	f.fy = append(f.fy, 0)
+	f.density = append(f.density, f.restDensity)
+	f.pressure = append(f.pressure, 0)
+	return true
+}
+
+//FillRect remplit le rectangle de coin pos de particules au repos, au pas Spacing
+// Retourne le nombre de particules ajoutées
+func (f *SPHFluid) FillRect(pos Vec2, width float64, height float64) int {
+	count := 0
+	step := f.Spacing()
+	for y := pos.Y + step/2; y < pos.Y+height; y += step {
+		for x := pos.X + step/2; x < pos.X+width; x += step {
+			if !f.Emit(Vec2{x, y}, Vec2{}) {
+				return count
+			}
+			count++
+		}
+	}
+	return count
+}
+
+//Count retourne le nombre de particules
+func (f *SPHFluid) Count() int {
+	return len(f.x)
+}
+
+//Position retourne la position de la particule i
+func (f *SPHFluid) Position(i int) Vec2 {
+	return Vec2{f.x[i], f.y[i]}
+}
+
+//Velocity retourne la vitesse de la particule i, par tick
+func (f *SPHFluid) Velocity(i int) Vec2 {
+	return Vec2{f.vx[i], f.vy[i]}
+}
+
+//Density retourne la densité de la particule i au dernier sous-pas
+func (f *SPHFluid) Density(i int) float64 {
+	return f.density[i]
+}
+
+//Pressure retourne la pression de la particule i au dernier sous-pas
+func (f *SPHFluid) Pressure(i int) float64 {
+	return f.pressure[i]
+}
+
+//Clear supprime toutes les particules
+func (f *SPHFluid) Clear() {
+	f.x, f.y = f.x[:0], f.y[:0]
+	f.vx, f.vy = f.vx[:0], f.vy[:0]
+	f.fx, f.fy = f.fx[:0], f.fy[:0]
+	f.density, f.pressure = f.density[:0], f.pressure[:0]
+}
+
+//poly6 noyau de lissage des densités, r2 étant la distance au carré
+func (f *SPHFluid) poly6(r2 float64) float64 {
+	h2 := f.h * f.h
+	if r2 >= h2 {
+		return 0
+	}
+	d := h2 - r2
+	return f.poly6K * d * d * d
+}
+
+//spikyGrad norme du gradient du noyau de pression, à la distance r
+func (f *SPHFluid) spikyGrad(r float64) float64 {
+	if r >= f.h {
+		return 0
+	}
+	d := f.h - r
+	return f.spikyK * d * d
+}
+
+//viscLaplacian laplacien du noyau de viscosité, à la distance r
+func (f *SPHFluid) viscLaplacian(r float64) float64 {
+	if r >= f.h {
+		return 0
+	}
+	return f.viscK * (f.h - r)
+}
+
+//cell retourne la cellule de la grille qui contient p
+func (f *SPHFluid) cell(p Vec2) cellKey {
+	return cellKey{int(math.Floor(p.X / f.h)), int(math.Floor(p.Y / f.h))}
+}
+
+//buildGrid range les particules dans la grille. Les tranches sont réutilisées
+func (f *SPHFluid) buildGrid() {
+	for k, list := range f.grid {
+		if len(list) == 0 {
+			delete(f.grid, k)
+			continue
+		}
+		f.grid[k] = list[:0]
+	}
+	for i := range f.x {
+		k := f.cell(f.Position(i))
+		f.grid[k] = append(f.grid[k], i)
+	}
+}
+
+//findNeighbors liste une fois par sous-pas les voisines à moins de h de chaque particule,
+// elle comprise. Les voisines de i sont neighbors[nbrStart[i]:nbrStart[i+1]]
+func (f *SPHFluid) findNeighbors() {
+	f.neighbors = f.neighbors[:0]
+	f.nbrStart = f.nbrStart[:0]
+	h2 := f.h * f.h
+	for i := range f.x {
+		f.nbrStart = append(f.nbrStart, len(f.neighbors))
+		p := f.Position(i)
+		c := f.cell(p)
+		for cx := c.x - 1; cx <= c.x+1; cx++ {
+			for cy := c.y - 1; cy <= c.y+1; cy++ {
+				for _, j := range f.grid[cellKey{cx, cy}] {
+					d := Vec2{p.X - f.x[j], p.Y - f.y[j]}
+					if r2 := d.X*d.X + d.Y*d.Y; r2 < h2 {
+						f.neighbors = append(f.neighbors, neighbor{j, d, math.Sqrt(r2)})
+					}
+				}
+			}
+		}
+	}
+	f.nbrStart = append(f.nbrStart, len(f.neighbors))
+}
+
+//computeDensities calcule densité et pression de chaque particule
+func (f *SPHFluid) computeDensities() {
+	for i := range f.x {
+		d := 0.0
+		for _, n := range f.neighbors[f.nbrStart[i]:f.nbrStart[i+1]] {
+			d += f.mass * f.poly6(n.r*n.r)
+		}
+		f.density[i] = d
+		// pas de pression négative: le fluide ne s'agglutine pas
+		f.pressure[i] = math.Max(0, f.stiffness*(d-f.restDensity))
+	}
+}
+
+//computeForces calcule l'accélération de pression et de viscosité de chaque particule
+func (f *SPHFluid) computeForces() {
+	for i := range f.x {
+		var ax, ay float64
+		for _, n := range f.neighbors[f.nbrStart[i]:f.nbrStart[i+1]] {
+			j := n.j
+			if j == i || n.r == 0 {
+				continue
+			}
+			dir := n.d.Div(n.r)
+			// pression symétrisée: poussée mutuelle égale et opposée
+			press := -f.mass * (f.pressure[i] + f.pressure[j]) / (2 * f.density[j]) * f.spikyGrad(n.r)
+			visc := f.viscosity * f.mass / f.density[j] * f.viscLaplacian(n.r)
+			ax += dir.X*press + visc*(f.vx[j]-f.vx[i])
+			ay += dir.Y*press + visc*(f.vy[j]-f.vy[i])
+		}
+		f.fx[i] = ax / f.density[i]
+		f.fy[i] = ay / f.density[i]
+	}
+}
+
+//update fait avancer le fluide d'un tick, en sous-pas
+func (f *SPHFluid) update(s *Space, obstacles []obstacle) {
+	if len(f.x) == 0 {
+		return
+	}
+	n := f.substepCount()
+	f.substeps = n
+	for k := 0; k < n; k++ {
+		f.substep(s, obstacles, 1/float64(n))
+	}
+}
+
+//substep intègre le fluide sur dt ticks (Euler symplectique) et le confine dans obstacles
+func (f *SPHFluid) substep(s *Space, obstacles []obstacle, dt float64) {
+	f.buildGrid()
+	f.findNeighbors()
+	f.computeDensities()
+	f.computeForces()
+
+	g := s.gravity.Mult(f.gravScale)
+	for i := range f.x {
+		pos := f.Position(i)
+		if len(s.attractors) > 0 {
+			g = s.gravityAt(pos, nil).Mult(f.gravScale)
+		}
+
+		v := Vec2{f.vx[i], f.vy[i]}.Add(Vec2{f.fx[i], f.fy[i]}.Add(g).Mult(dt))
+		start, prev := pos, pos
+		pos = pos.Add(v.Mult(dt))
+
+		// les formes dynamiques reçoivent la quantité de mouvement perdue par la particule
+		pos, prev = resolvePoint(pos, prev, start, dt, obstacles, f.bounce, f.friction, f.mass)
+		v = pos.Sub(prev).Div(dt)
+
+		f.x[i], f.y[i] = pos.X, pos.Y
+		f.vx[i], f.vy[i] = v.X, v.Y
+	}
+}
+
+//SPHFluids retourne la liste des fluides SPH de l'espace
+func (s *Space) SPHFluids() []*SPHFluid {
+	return s.sphFluids
+}
+
+//AddSPHFluid ajoute un fluide SPH à l'espace
+func (s *Space) AddSPHFluid(f *SPHFluid) {
+	s.sphFluids = append(s.sphFluids, f)
+}
+
+//RemoveSPHFluid supprime un fluide SPH de l'espace
+func (s *Space) RemoveSPHFluid(f *SPHFluid) {
+	for i, fluid := range s.sphFluids {
+		if fluid == f {
+			copy(s.sphFluids[i:], s.sphFluids[i+1:])
+			s.sphFluids[len(s.sphFluids)-1] = nil
+			s.sphFluids = s.sphFluids[:len(s.sphFluids)-1]
+			return
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package physics
+
+import "testing"
+
+//tankSpace retourne un espace avec un bac: un sol et deux murs statiques
+// aux coins accolés, l'intérieur allant de x 0 à 200 au-dessus de y 200
+func tankSpace() *Space {
+	s := &Space{}
+	s.SetGravity(Vec2{0, 0.5})
+	for _, r := range []*Rectangle{
+		NewRectangle(Vec2{0, 200}, 200, 20),
+		NewRectangle(Vec2{-20, 0}, 20, 220),
+		NewRectangle(Vec2{200, 0}, 20, 220),
+	} {
+		r.SetStatic(true)
+		s.AddShape(r)
+	}
+	return s
+}
+
+func TestSPHFluidStaysInTank(t *testing.T) {
+	// un pas trop long pour h diverge en quelques ticks, la fuite par les coins est plus lente
+	for _, c := range []struct {
+		h     float64
+		ticks int
+	}{{4, 150}, {8, 600}, {16, 150}} {
+		h := c.h
+		s := tankSpace()
+		f := NewSPHFluid(h)
+		f.FillRect(Vec2{0, 100}, 100, 100)
+		s.AddSPHFluid(f)
+
+		for tick := 0; tick < c.ticks; tick++ {
+			s.Update()
+			for i := 0; i < f.Count(); i++ {
+				if p := f.Position(i); !(p.X >= 0 && p.X < 200 && p.Y < 200) {
+					t.Fatalf("h %v, tick %d: particle %d left the tank at %v", h, tick, i, p)
+				}
+			}
+		}
+	}
+}